@@ -1,13 +1,21 @@
 package api
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/mrdankuta/aws-devops-api/auth"
 	"github.com/mrdankuta/aws-devops-api/config"
+	"github.com/mrdankuta/aws-devops-api/events"
 	"github.com/mrdankuta/aws-devops-api/tasks"
 	"golang.org/x/oauth2"
 )
@@ -16,13 +24,18 @@ type API struct {
 	config      *config.Config
 	authModule  *auth.AuthModule
 	taskManager *tasks.TaskManager
+	logLevel    *slog.LevelVar
 }
 
-func NewAPI(cfg *config.Config, authModule *auth.AuthModule, taskManager *tasks.TaskManager) *API {
+// NewAPI wires up the routed handlers. logLevel is the LevelVar backing the
+// shared slog.Logger (see logging.New); GetLogLevel/SetLogLevel let an admin
+// adjust verbosity at runtime without a restart.
+func NewAPI(cfg *config.Config, authModule *auth.AuthModule, taskManager *tasks.TaskManager, logLevel *slog.LevelVar) *API {
 	return &API{
 		config:      cfg,
 		authModule:  authModule,
 		taskManager: taskManager,
+		logLevel:    logLevel,
 	}
 }
 
@@ -34,8 +47,17 @@ func (api *API) SetupRoutes(router *mux.Router) {
 	}
 
 	// Auth routes (unprotected)
-	router.HandleFunc("/auth/login", api.authModule.StartOIDCFlow).Methods("GET")
-	router.HandleFunc("/auth/callback", api.authModule.HandleCallback).Methods("GET")
+	router.HandleFunc("/auth/{connector}/login", api.authModule.StartOIDCFlow).Methods("GET")
+	router.HandleFunc("/auth/callback", api.authModule.HandleCallback).Methods("GET", "POST")
+	router.HandleFunc("/auth/logout", api.authModule.Logout).Methods("POST")
+
+	// Inbound CloudEvents: external systems (alert pipelines, GitOps
+	// controllers) driving this have no browser session to present, so this
+	// route authenticates with an HMAC signature (see verifyEventSignature)
+	// instead of apiRouter's session-cookie authMiddleware. Registered
+	// directly on router, ahead of the "/api" prefix below, so it's matched
+	// before apiRouter would otherwise claim it.
+	router.HandleFunc("/api/events", api.HandleInboundEvent).Methods("POST")
 
 	// Protected API routes
 	apiRouter := router.PathPrefix("/api").Subrouter()
@@ -46,8 +68,11 @@ func (api *API) SetupRoutes(router *mux.Router) {
 	apiRouter.HandleFunc("/tasks/{id}", api.UpdateTask).Methods("PUT")
 	apiRouter.HandleFunc("/tasks/{id}", api.DeleteTask).Methods("DELETE")
 	apiRouter.HandleFunc("/tasks/{id}/execute", api.ExecuteTask).Methods("POST")
+	apiRouter.HandleFunc("/tasks/{id}/history", api.GetTaskHistory).Methods("GET")
 	apiRouter.HandleFunc("/settings", api.GetSettings).Methods("GET")
 	apiRouter.HandleFunc("/settings", api.UpdateSettings).Methods("PUT")
+	apiRouter.HandleFunc("/admin/log-level", api.GetLogLevel).Methods("GET")
+	apiRouter.HandleFunc("/admin/log-level", api.SetLogLevel).Methods("PUT")
 }
 
 var bypassAuth bool = true // Set to true for testing. Remove this before production.
@@ -59,12 +84,16 @@ func (api *API) authMiddleware(next http.Handler) http.Handler {
 			next.ServeHTTP(w, r)
 			return
 		}
-		// Check if the user is authenticated
-		// TODO: This is a simplified check. Implement proper token validation.
-		if !api.authModule.HasValidToken(r.Header.Get("Authorization")) {
+		// Check if the request carries a valid session cookie from a
+		// completed OIDC/SAML callback, and attach its session ID to the
+		// request context so downstream AWS calls authorize and cache
+		// tokens under the session that actually owns them.
+		sessionID, ok := api.authModule.SessionID(r)
+		if !ok {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
+		r = r.WithContext(auth.ContextWithSessionID(r.Context(), sessionID))
 		next.ServeHTTP(w, r)
 	})
 }
@@ -125,7 +154,7 @@ func (api *API) ExecuteTask(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	taskID := vars["id"]
 
-	result, err := api.taskManager.ExecuteTask(taskID)
+	result, err := api.taskManager.ExecuteTask(r.Context(), taskID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -135,6 +164,69 @@ func (api *API) ExecuteTask(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"result": result})
 }
 
+func (api *API) GetTaskHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	taskID := vars["id"]
+
+	history, err := api.taskManager.GetTaskHistory(taskID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(history)
+}
+
+// HandleInboundEvent accepts a CloudEvent of type com.aws-devops-api.task.execute
+// and runs the task named in data.taskId out-of-band from cron, so external
+// systems (alert pipelines, GitOps controllers) can drive on-demand runs.
+// It is not behind apiRouter's session-cookie authMiddleware (those systems
+// have no browser session to present); instead the body must be signed per
+// verifyEventSignature. It runs as the configured system account, same as a
+// scheduled cron firing (see auth.AuthModule.SystemSessionID).
+func (api *API) HandleInboundEvent(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !verifyEventSignature(api.config.Events.InboundSecret, body, r.Header.Get("X-Signature")) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var event events.Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if event.Type != events.TypeTaskExecute {
+		http.Error(w, fmt.Sprintf("unsupported event type: %s", event.Type), http.StatusBadRequest)
+		return
+	}
+
+	var data events.TaskExecuteData
+	if err := json.Unmarshal(event.Data, &data); err != nil {
+		http.Error(w, "invalid event data: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if data.TaskID == "" {
+		http.Error(w, "event data.taskId is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx := auth.ContextWithSessionID(r.Context(), api.authModule.SystemSessionID())
+	result, err := api.taskManager.ExecuteTask(ctx, data.TaskID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"result": result})
+}
+
 func (api *API) GetSettings(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(api.config)
 }
@@ -147,7 +239,7 @@ func (api *API) UpdateSettings(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Update the configuration
-	api.config.OIDC = updatedConfig.OIDC
+	api.config.Auth = updatedConfig.Auth
 	api.config.Slack = updatedConfig.Slack
 
 	// Save the updated configuration to file
@@ -159,6 +251,52 @@ func (api *API) UpdateSettings(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// GetLogLevel reports the current level of the shared slog.Logger.
+func (api *API) GetLogLevel(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]string{"level": api.logLevel.Level().String()})
+}
+
+// SetLogLevel adjusts the shared slog.Logger's level in place, so operators
+// can turn on debug logging for auth/iam/s3/slack without a restart.
+func (api *API) SetLogLevel(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Level string `json:"level"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(body.Level)); err != nil {
+		http.Error(w, fmt.Sprintf("invalid level %q: %v", body.Level, err), http.StatusBadRequest)
+		return
+	}
+
+	api.logLevel.Set(level)
+	w.WriteHeader(http.StatusOK)
+}
+
+// verifyEventSignature reports whether header is a valid "sha256=<hex>"
+// HMAC-SHA256 of body under secret, the same convention notify/webhook.go
+// uses to sign outbound deliveries. An empty secret always fails closed, so
+// POST /api/events is disabled (returns 401) until an operator configures
+// events.inbound_secret.
+func verifyEventSignature(secret string, body []byte, header string) bool {
+	if secret == "" {
+		return false
+	}
+	const prefix = "sha256="
+	if !strings.HasPrefix(header, prefix) {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(strings.TrimPrefix(header, prefix)), []byte(expected))
+}
+
 // TODO: Remove this before production
 func (api *API) generateTestToken(w http.ResponseWriter, r *http.Request) {
 	// Generate a dummy token for testing
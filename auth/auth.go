@@ -5,12 +5,16 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -18,130 +22,498 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
-	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gorilla/mux"
 	devconfig "github.com/mrdankuta/aws-devops-api/config"
+	"golang.org/x/crypto/hkdf"
 	"golang.org/x/oauth2"
 )
 
+// encryptionKeySize is the AES-256 key size in bytes.
+const encryptionKeySize = 32
+
+// TokenSink persists one account's encrypted token blob out-of-band so it
+// can be rehydrated across restarts, following the same out-of-band sink
+// pattern as Vault agent auto-auth. Implementations must be safe for
+// concurrent use. A nil TokenSink is valid and keeps AuthModule's legacy
+// in-memory-only behavior.
+type TokenSink interface {
+	// Write persists blob (an encrypted token) for accountID, overwriting
+	// any previous value.
+	Write(ctx context.Context, accountID string, blob []byte) error
+	// Read returns the persisted blob for accountID, or an error satisfying
+	// errors.Is(err, ErrNotFound) if none exists.
+	Read(ctx context.Context, accountID string) ([]byte, error)
+	// Delete removes the persisted blob for accountID, if any.
+	Delete(ctx context.Context, accountID string) error
+	// List returns every accountID with a persisted blob, so AuthModule can
+	// rehydrate its in-memory cache on startup.
+	List(ctx context.Context) ([]string, error)
+}
+
+// ErrNotFound is returned by TokenSink.Read when accountID has no persisted blob.
+var ErrNotFound = errors.New("token not found")
+
 type AuthModule struct {
-	oidcConfig    *oauth2.Config
-	oidcProvider  *oidc.Provider
+	connectors    map[string]Connector
 	tokenCache    sync.Map
 	encryptionKey []byte
-	stateStore    sync.Map
-	verifier      *oidc.IDTokenVerifier
-	httpClient    *http.Client
+	tokenSink     TokenSink
+	// stateStore maps a login attempt's CSRF state to the connector it was
+	// started against and when it expires, so the shared /auth/callback
+	// route knows which connector to dispatch the response to, and the
+	// janitor knows which abandoned entries to reap.
+	stateStore sync.Map
+	// sessionStore maps a session cookie's ID to the account it authenticates,
+	// so HTTP handlers can resolve "who is this request from" without the
+	// client presenting a bearer token on every call.
+	sessionStore sync.Map
+	sessionTTL   time.Duration
+	cookieSecure bool
+	// systemScopes are the scopes AuthConfig.SystemAccount grants to
+	// scheduled/inbound-event task runs, which authorize under
+	// systemSessionID instead of a browser session (see SystemSessionID).
+	systemScopes []string
+	httpClient   *http.Client
+	logger       *slog.Logger
+
+	stopJanitor chan struct{}
+}
+
+// stateTTL bounds how long an in-flight login attempt's state is honored;
+// the janitor reaps anything older.
+const stateTTL = 5 * time.Minute
+
+// defaultSessionTTL is used when devconfig.SessionConfig.TTL is unset or
+// invalid.
+const defaultSessionTTL = 24 * time.Hour
+
+// janitorInterval is how often the janitor sweeps stateStore, tokenCache,
+// and sessionStore.
+const janitorInterval = 1 * time.Minute
+
+// stateCookieName holds the CSRF state StartOIDCFlow generated, so
+// HandleCallback can confirm the browser completing the flow is the same one
+// that started it (the state query/RelayState param alone only proves the
+// callback saw a value AuthModule generated, not that it's talking to the
+// same browser).
+const stateCookieName = "oidc_state"
+
+// sessionCookieName holds the ID of the session created on a successful
+// callback.
+const sessionCookieName = "session"
+
+// systemSessionID is the session key scheduled cron firings and inbound
+// task.execute CloudEvents authorize under, since neither has a browser
+// session to derive one from. It can never collide with a real session ID
+// (those come from generateRandomState, base64 of 32 random bytes).
+const systemSessionID = "system"
+
+// stateEntry is the value stored in stateStore for one in-flight login
+// attempt.
+type stateEntry struct {
+	ConnectorID string
+	ExpiresAt   time.Time
 }
 
 type tokenCacheEntry struct {
-	Token     *oauth2.Token
-	AccountID string
+	Blob      []byte
+	SessionID string
 }
 
-func NewAuthModule(cfg *devconfig.OIDCConfig) (*AuthModule, error) {
-	ctx := context.Background()
-	provider, err := oidc.NewProvider(ctx, cfg.ProviderURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create OIDC provider: %w", err)
-	}
+// sessionEntry is the value stored in sessionStore for one issued session
+// cookie.
+type sessionEntry struct {
+	AccountID   string
+	ConnectorID string
+	ExpiresAt   time.Time
+}
 
-	oidcConfig := &oauth2.Config{
-		ClientID:     cfg.ClientID,
-		ClientSecret: cfg.ClientSecret,
-		Endpoint:     provider.Endpoint(),
-		RedirectURL:  cfg.RedirectURL,
-		Scopes:       []string{oidc.ScopeOpenID, "profile", "email"},
+// NewAuthModule builds a Connector for every entry in connectorConfigs and
+// loads the AES key that encrypts cached tokens from encryptionCfg. sink, if
+// non-nil, persists encrypted tokens out-of-band (see TokenSink); on startup
+// every blob it holds is rehydrated into the in-memory cache. logger is used
+// for every auth event (oidc_flow_started, callback_state_mismatch,
+// token_refreshed, token_decrypt_failed, ...); a nil logger falls back to
+// slog.Default(). sessionCfg controls the cookie issued on a successful
+// callback (see SessionAccountID).
+func NewAuthModule(connectorConfigs []devconfig.ConnectorConfig, encryptionCfg devconfig.TokenEncryptionConfig, sink TokenSink, logger *slog.Logger, sessionCfg devconfig.SessionConfig, systemAccountCfg devconfig.SystemAccountConfig) (*AuthModule, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	connectors := make(map[string]Connector, len(connectorConfigs))
+	for _, cfg := range connectorConfigs {
+		connector, err := buildConnector(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build connector %s: %w", cfg.ID, err)
+		}
+		connectors[cfg.ID] = connector
 	}
 
-	// Generate a random encryption key
-	encryptionKey := make([]byte, 32)
-	if _, err := io.ReadFull(rand.Reader, encryptionKey); err != nil {
-		return nil, fmt.Errorf("failed to generate encryption key: %w", err)
+	encryptionKey, err := loadEncryptionKey(encryptionCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load token encryption key: %w", err)
 	}
 
-	return &AuthModule{
-		oidcConfig:    oidcConfig,
-		oidcProvider:  provider,
+	am := &AuthModule{
+		connectors:    connectors,
 		encryptionKey: encryptionKey,
-		verifier:      provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+		tokenSink:     sink,
+		sessionTTL:    resolveSessionTTL(sessionCfg.TTL, logger),
+		cookieSecure:  !sessionCfg.Insecure,
+		systemScopes:  systemAccountCfg.Scopes,
 		httpClient:    &http.Client{Timeout: 10 * time.Second},
-	}, nil
+		logger:        logger,
+		stopJanitor:   make(chan struct{}),
+	}
+
+	if sink != nil {
+		am.rehydrate(context.Background())
+	}
+
+	go am.janitor()
+
+	return am, nil
 }
 
-func (am *AuthModule) GetAuthorizationURL(state string) string {
-	am.stateStore.Store(state, time.Now().Add(5*time.Minute))
-	return am.oidcConfig.AuthCodeURL(state)
+// Close stops the background janitor. It does not close the TokenSink.
+func (am *AuthModule) Close() {
+	close(am.stopJanitor)
 }
 
-func (am *AuthModule) StartOIDCFlow(w http.ResponseWriter, r *http.Request) {
-	state, _ := generateRandomState()
-	nonce, _ := generateRandomNonce()
+// buildConnector constructs the Connector for one configured upstream IdP.
+// "google" and "gitlab" are plain OIDC providers, with well-known issuers
+// defaulted here so operators don't have to look them up.
+func buildConnector(cfg devconfig.ConnectorConfig) (Connector, error) {
+	switch cfg.Type {
+	case "oidc":
+		return NewOIDCConnector(cfg.ID, cfg)
+	case "google":
+		if cfg.ProviderURL == "" {
+			cfg.ProviderURL = "https://accounts.google.com"
+		}
+		return NewOIDCConnector(cfg.ID, cfg)
+	case "gitlab":
+		if cfg.ProviderURL == "" {
+			cfg.ProviderURL = "https://gitlab.com"
+		}
+		return NewOIDCConnector(cfg.ID, cfg)
+	case "github":
+		return NewGitHubConnector(cfg.ID, cfg.ClientID, cfg.ClientSecret, cfg.RedirectURL), nil
+	case "saml":
+		if !cfg.SAMLAllowUnverified {
+			return nil, fmt.Errorf("connector %s: saml connector does not verify assertion signatures; set saml_allow_unverified: true to use it anyway", cfg.ID)
+		}
+		return NewSAMLConnector(cfg.ID, cfg.SAMLSSOURL, cfg.SAMLEntityID, cfg.SAMLACSURL), nil
+	default:
+		return nil, fmt.Errorf("unknown connector type: %s", cfg.Type)
+	}
+}
 
-	// Store state and nonce (you might want to use a secure session store instead of the stateStore)
-	am.stateStore.Store(state, nonce)
+// rehydrate loads every blob held by the configured TokenSink into the
+// in-memory cache, so a restart doesn't force every user to re-authenticate.
+// Note this only helps while the session ID a blob is keyed by is still
+// live; sessionStore itself isn't persisted, so a restart still forces
+// every existing session to re-login, at which point its new session gets
+// its own freshly stored token anyway.
+func (am *AuthModule) rehydrate(ctx context.Context) {
+	sessionIDs, err := am.tokenSink.List(ctx)
+	if err != nil {
+		am.logger.Error("failed to list persisted tokens", "error", err)
+		return
+	}
 
-	// Redirect to Keycloak login
-	authURL := am.oidcConfig.AuthCodeURL(state, oidc.Nonce(nonce))
-	http.Redirect(w, r, authURL, http.StatusFound)
+	for _, sessionID := range sessionIDs {
+		blob, err := am.tokenSink.Read(ctx, sessionID)
+		if err != nil {
+			am.logger.Error("failed to read persisted token", "session_id", sessionID, "error", err)
+			continue
+		}
+		am.tokenCache.Store(sessionID, tokenCacheEntry{Blob: blob, SessionID: sessionID})
+	}
+	am.logger.Info("rehydrated tokens from sink", "count", len(sessionIDs))
 }
 
-func (am *AuthModule) HandleCallback(w http.ResponseWriter, r *http.Request) {
-	state := r.URL.Query().Get("state")
-	code := r.URL.Query().Get("code")
+// loadEncryptionKey resolves the AES-256 key used to encrypt cached tokens
+// from a stable source, so it survives a restart. It tries KeyEnv, then
+// KeyFile, then deriving a key from PassphraseEnv via HKDF-SHA256. If none
+// are configured, it falls back to a random per-boot key, same as before,
+// which means a restart invalidates every cached token.
+func loadEncryptionKey(cfg devconfig.TokenEncryptionConfig) ([]byte, error) {
+	switch {
+	case cfg.KeyEnv != "":
+		encoded := os.Getenv(cfg.KeyEnv)
+		if encoded == "" {
+			return nil, fmt.Errorf("encryption key env var %s is not set", cfg.KeyEnv)
+		}
+		return decodeEncryptionKey(encoded)
+
+	case cfg.KeyFile != "":
+		data, err := os.ReadFile(cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read encryption key file %s: %w", cfg.KeyFile, err)
+		}
+		return decodeEncryptionKey(strings.TrimSpace(string(data)))
+
+	case cfg.PassphraseEnv != "":
+		passphrase := os.Getenv(cfg.PassphraseEnv)
+		if passphrase == "" {
+			return nil, fmt.Errorf("passphrase env var %s is not set", cfg.PassphraseEnv)
+		}
+		key := make([]byte, encryptionKeySize)
+		kdf := hkdf.New(sha256.New, []byte(passphrase), []byte("aws-devops-api-token-sink"), nil)
+		if _, err := io.ReadFull(kdf, key); err != nil {
+			return nil, fmt.Errorf("failed to derive encryption key: %w", err)
+		}
+		return key, nil
+
+	default:
+		slog.Warn("no token_encryption source configured; generating a random key for this boot, every restart will force re-authentication")
+		key := make([]byte, encryptionKeySize)
+		if _, err := io.ReadFull(rand.Reader, key); err != nil {
+			return nil, fmt.Errorf("failed to generate encryption key: %w", err)
+		}
+		return key, nil
+	}
+}
+
+// resolveSessionTTL parses s with time.ParseDuration, falling back to
+// defaultSessionTTL if s is empty or invalid.
+func resolveSessionTTL(s string, logger *slog.Logger) time.Duration {
+	if s == "" {
+		return defaultSessionTTL
+	}
+	ttl, err := time.ParseDuration(s)
+	if err != nil {
+		logger.Warn("invalid session ttl, using default", "value", s, "default", defaultSessionTTL, "error", err)
+		return defaultSessionTTL
+	}
+	return ttl
+}
+
+func decodeEncryptionKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to base64-decode encryption key: %w", err)
+	}
+	if len(key) != encryptionKeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes, got %d", encryptionKeySize, len(key))
+	}
+	return key, nil
+}
 
-	storedNonce, ok := am.stateStore.Load(state)
+// StartOIDCFlow redirects to the {connector} path segment's upstream login,
+// recording a CSRF state that HandleCallback uses to recover which
+// connector to dispatch the response to. Despite the name (kept for route
+// compatibility), it drives any configured connector, not just OIDC.
+//
+// The same state is also set as an HttpOnly cookie scoped to /auth/callback,
+// so HandleCallback can confirm it's talking to the same browser that
+// started the flow: the state/RelayState param alone proves the callback
+// saw a value this process generated, but not that the browser presenting it
+// is the one the login link was sent to, which is what actually stops an
+// attacker from tricking a victim into completing the attacker's login
+// (login CSRF).
+func (am *AuthModule) StartOIDCFlow(w http.ResponseWriter, r *http.Request) {
+	connectorID := mux.Vars(r)["connector"]
+	connector, ok := am.connectors[connectorID]
 	if !ok {
-		http.Error(w, "Invalid state parameter", http.StatusBadRequest)
+		http.Error(w, fmt.Sprintf("unknown connector: %s", connectorID), http.StatusNotFound)
 		return
 	}
-	am.stateStore.Delete(state)
 
-	token, err := am.oidcConfig.Exchange(r.Context(), code)
+	state, err := generateRandomState()
 	if err != nil {
-		http.Error(w, "Failed to exchange token: "+err.Error(), http.StatusInternalServerError)
+		am.logger.Error("failed to generate oidc state", "connector", connectorID, "error", err)
+		http.Error(w, "Failed to generate state", http.StatusInternalServerError)
+		return
+	}
+	am.stateStore.Store(state, stateEntry{ConnectorID: connectorID, ExpiresAt: time.Now().Add(stateTTL)})
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookieName,
+		Value:    state,
+		Path:     "/auth/callback",
+		Expires:  time.Now().Add(stateTTL),
+		HttpOnly: true,
+		Secure:   am.cookieSecure,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	loginURL, err := connector.LoginURL(state)
+	if err != nil {
+		am.logger.Error("failed to build login url", "connector", connectorID, "error", err)
+		http.Error(w, "Failed to start login", http.StatusInternalServerError)
 		return
 	}
 
-	rawIDToken, ok := token.Extra("id_token").(string)
-	if !ok {
-		http.Error(w, "No id_token in token response", http.StatusInternalServerError)
+	am.logger.Info("oidc_flow_started", "connector", connectorID)
+	http.Redirect(w, r, loginURL, http.StatusFound)
+}
+
+func (am *AuthModule) HandleCallback(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		am.logger.Error("failed to parse callback request", "error", err)
+		http.Error(w, "Failed to parse callback request: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	idToken, err := am.verifier.Verify(r.Context(), rawIDToken)
-	if err != nil {
-		http.Error(w, "Failed to verify ID token: "+err.Error(), http.StatusInternalServerError)
+	// OIDC and GitHub return state as a query param; SAML's HTTP-POST
+	// binding names the equivalent field RelayState instead.
+	state := r.FormValue("state")
+	if state == "" {
+		state = r.FormValue("RelayState")
+	}
+
+	stateCookie, err := r.Cookie(stateCookieName)
+	if err != nil || stateCookie.Value == "" || stateCookie.Value != state {
+		am.logger.Warn("callback_state_mismatch", "reason", "missing or mismatched csrf cookie")
+		http.Error(w, "Invalid or expired state parameter", http.StatusBadRequest)
 		return
 	}
+	clearCookie(w, stateCookieName, "/auth/callback", am.cookieSecure)
 
-	if idToken.Nonce != storedNonce.(string) {
-		http.Error(w, "Invalid nonce", http.StatusBadRequest)
+	entryVal, ok := am.stateStore.LoadAndDelete(state)
+	if !ok {
+		am.logger.Warn("callback_state_mismatch", "reason", "unknown state")
+		http.Error(w, "Invalid or expired state parameter", http.StatusBadRequest)
+		return
+	}
+	entry := entryVal.(stateEntry)
+	if time.Now().After(entry.ExpiresAt) {
+		am.logger.Warn("callback_state_mismatch", "connector", entry.ConnectorID, "reason", "expired state")
+		http.Error(w, "Invalid or expired state parameter", http.StatusBadRequest)
 		return
 	}
+	connectorID := entry.ConnectorID
 
-	var claims struct {
-		Email string `json:"email"`
+	connector, ok := am.connectors[connectorID]
+	if !ok {
+		am.logger.Error("callback for unknown connector", "connector", connectorID)
+		http.Error(w, fmt.Sprintf("unknown connector: %s", connectorID), http.StatusInternalServerError)
+		return
 	}
-	if err := idToken.Claims(&claims); err != nil {
-		http.Error(w, "Failed to extract claims: "+err.Error(), http.StatusInternalServerError)
+
+	identity, token, err := connector.HandleCallback(r)
+	if err != nil {
+		am.logger.Error("authentication failed", "connector", connectorID, "error", err)
+		http.Error(w, "Authentication failed: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	encryptedToken, err := am.encryptToken(token)
+	sessionID, err := am.createSession(w, connectorID, identity.Email)
 	if err != nil {
-		http.Error(w, "Failed to encrypt token: "+err.Error(), http.StatusInternalServerError)
+		am.logger.Error("failed to create session", "connector", connectorID, "email", identity.Email, "error", err)
+		http.Error(w, "Failed to create session: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Tokens are cached under the session ID, not the claimed email: the
+	// email is whatever the upstream IdP says it is, but the session ID is
+	// the one thing tied to the browser that actually completed this login
+	// (see the state cookie check above). Keying the cache by email would
+	// let any authenticated browser drive AWS calls for another user's
+	// account just by knowing their email.
+	if err := am.storeToken(r.Context(), connectorID, sessionID, token, identity.Scopes); err != nil {
+		am.logger.Error("failed to store token", "connector", connectorID, "email", identity.Email, "error", err)
+		http.Error(w, "Failed to store token: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
-	am.tokenCache.Store(claims.Email, tokenCacheEntry{Token: encryptedToken, AccountID: claims.Email})
 
+	am.logger.Info("authenticated", "connector", connectorID, "email", identity.Email)
 	// Redirect to a success page or return a success message
-	fmt.Fprintf(w, "Authentication successful for %s", claims.Email)
+	fmt.Fprintf(w, "Authentication successful for %s via %s", identity.Email, connectorID)
+}
+
+// createSession mints a session ID bound to accountID, stores it in
+// sessionStore, and sets it as an HttpOnly cookie, so the caller's browser
+// can be recognized by SessionID/SessionAccountID on later requests instead
+// of presenting a bearer token. It returns the minted session ID so the
+// caller can also key the token cache on it (see HandleCallback).
+func (am *AuthModule) createSession(w http.ResponseWriter, connectorID, accountID string) (string, error) {
+	sessionID, err := generateRandomState()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate session id: %w", err)
+	}
+
+	expiresAt := time.Now().Add(am.sessionTTL)
+	am.sessionStore.Store(sessionID, sessionEntry{AccountID: accountID, ConnectorID: connectorID, ExpiresAt: expiresAt})
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    sessionID,
+		Path:     "/",
+		Expires:  expiresAt,
+		HttpOnly: true,
+		Secure:   am.cookieSecure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return sessionID, nil
 }
 
-func (am *AuthModule) HasValidToken(accountID string) bool {
-	// TODO: This is a simplified check. Implement proper token validation.
-	_, err := am.getOIDCToken(context.Background(), accountID)
-	return err == nil
+// SessionID returns r's session cookie value, if it names a live
+// (non-expired) entry in sessionStore. Handlers that kick off further work
+// on the caller's behalf (e.g. api.ExecuteTask) attach this to the context
+// they pass down via ContextWithSessionID, so GetAWSConfig/Authorize calls
+// made later in that chain (from tasks/pipeline.go) resolve the same
+// cached token.
+func (am *AuthModule) SessionID(r *http.Request) (string, bool) {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil || cookie.Value == "" {
+		return "", false
+	}
+
+	entryVal, ok := am.sessionStore.Load(cookie.Value)
+	if !ok {
+		return "", false
+	}
+	entry := entryVal.(sessionEntry)
+	if time.Now().After(entry.ExpiresAt) {
+		am.sessionStore.Delete(cookie.Value)
+		return "", false
+	}
+	return cookie.Value, true
+}
+
+// SessionAccountID resolves the account ID bound to r's session cookie, if
+// any is present and not expired. api.authMiddleware uses this to recognize
+// an already-authenticated browser.
+func (am *AuthModule) SessionAccountID(r *http.Request) (string, bool) {
+	sessionID, ok := am.SessionID(r)
+	if !ok {
+		return "", false
+	}
+	entryVal, _ := am.sessionStore.Load(sessionID)
+	return entryVal.(sessionEntry).AccountID, true
+}
+
+// SystemSessionID returns the session key non-interactive task runs
+// (scheduled cron firings, inbound task.execute CloudEvents) should attach
+// to their context via ContextWithSessionID. Authorize and GetAWSConfig
+// recognize it and run the system account path instead of looking up a
+// cached OIDC token.
+func (am *AuthModule) SystemSessionID() string {
+	return systemSessionID
+}
+
+// Logout deletes r's session, if any, and clears its cookie.
+func (am *AuthModule) Logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		am.sessionStore.Delete(cookie.Value)
+	}
+	clearCookie(w, sessionCookieName, "/", am.cookieSecure)
+	w.WriteHeader(http.StatusOK)
+}
+
+// clearCookie overwrites the named cookie with an already-expired one, so
+// the browser drops it.
+func clearCookie(w http.ResponseWriter, name, path string, secure bool) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    "",
+		Path:     path,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	})
 }
 
 // CustomTokenRetriever implements the stscreds.IdentityTokenRetriever interface
@@ -153,8 +525,22 @@ func (ctr CustomTokenRetriever) GetIdentityToken() ([]byte, error) {
 	return []byte(ctr.Token), nil
 }
 
-func (am *AuthModule) GetAWSConfig(ctx context.Context, accountID, roleARN string) (aws.Config, error) {
-	token, err := am.getOIDCToken(ctx, accountID)
+// GetAWSConfig exchanges the OIDC token cached under sessionID (see
+// SessionID) for AWS credentials scoped to roleARN. sessionID identifies
+// the authenticated browser session the call is made on behalf of; it is
+// not an AWS account number — roleARN already encodes which AWS account
+// and role to assume.
+//
+// sessionID == SystemSessionID() is handled separately by
+// getSystemAWSConfig: scheduled/inbound-event runs have no OIDC token to
+// exchange, so roleARN is assumed directly from the process's own ambient
+// AWS identity instead.
+func (am *AuthModule) GetAWSConfig(ctx context.Context, sessionID, roleARN string) (aws.Config, error) {
+	if sessionID == systemSessionID {
+		return am.getSystemAWSConfig(ctx, roleARN)
+	}
+
+	token, err := am.getOIDCToken(ctx, sessionID)
 	if err != nil {
 		// TODO: Instead of returning an error, you might want to trigger the authentication flow here
 		// For now, we'll just return the error
@@ -176,48 +562,167 @@ func (am *AuthModule) GetAWSConfig(ctx context.Context, accountID, roleARN strin
 	return cfg, nil
 }
 
-func (am *AuthModule) getOIDCToken(ctx context.Context, accountID string) (*oauth2.Token, error) {
-	entry, ok := am.tokenCache.Load(accountID)
-	if !ok {
-		return nil, fmt.Errorf("no token found for account %s", accountID)
+// getSystemAWSConfig assumes roleARN using whatever AWS identity the
+// process itself already runs as (an EC2 instance role, ECS task role, or
+// IRSA pod role, resolved by the default credential chain), the same way
+// any other automation on that host would assume a role. This is what lets
+// scheduled task runs and inbound task.execute events (tasks/task_manager.go,
+// api.HandleInboundEvent) get AWS credentials without a per-user OIDC token.
+func (am *AuthModule) getSystemAWSConfig(ctx context.Context, roleARN string) (aws.Config, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to load AWS config: %w", err)
 	}
 
-	tokenEntry := entry.(tokenCacheEntry)
-	token, err := am.decryptToken(tokenEntry.Token)
+	stsSvc := sts.NewFromConfig(cfg)
+	cfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(stsSvc, roleARN))
+
+	return cfg, nil
+}
+
+func (am *AuthModule) getOIDCToken(ctx context.Context, sessionID string) (*oauth2.Token, error) {
+	blob, err := am.loadBlob(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := am.decryptToken(blob)
 	if err != nil {
+		am.logger.Error("token_decrypt_failed", "session_id", sessionID, "error", err)
 		return nil, fmt.Errorf("failed to decrypt token: %w", err)
 	}
 
-	if token.Valid() {
-		return token, nil
+	if stored.Token.Valid() {
+		return stored.Token, nil
+	}
+
+	connector, ok := am.connectors[stored.ConnectorID]
+	if !ok {
+		return nil, fmt.Errorf("token for session %s came from unknown connector %s", sessionID, stored.ConnectorID)
 	}
 
 	// Token is expired, try to refresh
-	newToken, err := am.refreshToken(ctx, token)
+	newToken, err := connector.Refresh(ctx, stored.Token)
 	if err != nil {
 		return nil, fmt.Errorf("failed to refresh token: %w", err)
 	}
 
-	encryptedToken, err := am.encryptToken(newToken)
-	if err != nil {
-		return nil, fmt.Errorf("failed to encrypt refreshed token: %w", err)
+	if err := am.storeToken(ctx, stored.ConnectorID, sessionID, newToken, stored.Scopes); err != nil {
+		return nil, fmt.Errorf("failed to store refreshed token: %w", err)
 	}
-	am.tokenCache.Store(accountID, tokenCacheEntry{Token: encryptedToken, AccountID: accountID})
 
+	am.logger.Info("token_refreshed", "connector", stored.ConnectorID, "session_id", sessionID)
 	return newToken, nil
 }
 
-func (am *AuthModule) refreshToken(ctx context.Context, token *oauth2.Token) (*oauth2.Token, error) {
-	src := am.oidcConfig.TokenSource(ctx, token)
-	newToken, err := src.Token()
+// ErrForbidden is returned by Authorize when the session's granted scopes
+// don't cover every scope in requiredScopes.
+var ErrForbidden = errors.New("forbidden: missing required scope")
+
+// Authorize checks that the token cached under sessionID (see SessionID)
+// was granted every scope in requiredScopes, per the scopes extracted from
+// its connector's ID token at login (see ConnectorConfig.ScopesClaim), and
+// returns the full set of scopes it was granted. Callers that dispatch to
+// service commands should call this before invoking the command; the
+// returned scopes let the command pick a role ARN with no more access than
+// the caller was actually granted (see services/iam and services/s3's
+// selectRole).
+//
+// sessionID == SystemSessionID() is checked against AuthConfig.SystemAccount
+// .Scopes instead, since scheduled/inbound-event runs have no cached token.
+func (am *AuthModule) Authorize(ctx context.Context, sessionID string, requiredScopes []string) ([]string, error) {
+	if sessionID == systemSessionID {
+		return checkGrantedScopes(am.systemScopes, requiredScopes)
+	}
+
+	blob, err := am.loadBlob(ctx, sessionID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to refresh token: %w", err)
+		return nil, fmt.Errorf("not authenticated: %w", err)
 	}
-	return newToken, nil
+
+	stored, err := am.decryptToken(blob)
+	if err != nil {
+		am.logger.Error("token_decrypt_failed", "session_id", sessionID, "error", err)
+		return nil, fmt.Errorf("failed to decrypt token: %w", err)
+	}
+
+	return checkGrantedScopes(stored.Scopes, requiredScopes)
+}
+
+// checkGrantedScopes returns granted if it covers every scope in
+// requiredScopes, else ErrForbidden naming the first one missing.
+func checkGrantedScopes(granted []string, requiredScopes []string) ([]string, error) {
+	grantedSet := make(map[string]bool, len(granted))
+	for _, scope := range granted {
+		grantedSet[scope] = true
+	}
+
+	for _, required := range requiredScopes {
+		if !grantedSet[required] {
+			return nil, fmt.Errorf("%w: %s", ErrForbidden, required)
+		}
+	}
+	return granted, nil
+}
+
+// loadBlob returns sessionID's encrypted token blob from the in-memory
+// cache, falling back to the configured TokenSink (and repopulating the
+// cache) so a cache miss on one replica doesn't force re-authentication
+// when another replica already persisted the token.
+func (am *AuthModule) loadBlob(ctx context.Context, sessionID string) ([]byte, error) {
+	if entry, ok := am.tokenCache.Load(sessionID); ok {
+		return entry.(tokenCacheEntry).Blob, nil
+	}
+
+	if am.tokenSink == nil {
+		return nil, fmt.Errorf("no token found for session %s", sessionID)
+	}
+
+	blob, err := am.tokenSink.Read(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("no token found for session %s: %w", sessionID, err)
+	}
+	am.tokenCache.Store(sessionID, tokenCacheEntry{Blob: blob, SessionID: sessionID})
+	return blob, nil
+}
+
+// storeToken encrypts token (tagged with the connector it came from, so a
+// later refresh knows where to send it, and the scopes granted at login)
+// and writes it to both the in-memory cache and, if configured, the
+// TokenSink, keyed by sessionID so a cache hit proves the caller is the
+// browser session that completed the login, not just someone who knows the
+// authenticated user's email.
+func (am *AuthModule) storeToken(ctx context.Context, connectorID, sessionID string, token *oauth2.Token, scopes []string) error {
+	blob, err := am.encryptToken(connectorID, token, scopes)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt token: %w", err)
+	}
+
+	am.tokenCache.Store(sessionID, tokenCacheEntry{Blob: blob, SessionID: sessionID})
+
+	if am.tokenSink != nil {
+		if err := am.tokenSink.Write(ctx, sessionID, blob); err != nil {
+			return fmt.Errorf("failed to persist token for session %s: %w", sessionID, err)
+		}
+	}
+	return nil
+}
+
+// storedToken is the plaintext sealed inside an encrypted token blob,
+// pairing the OAuth2 token with the ID of the connector that issued it (so a
+// later refresh is dispatched to the right upstream IdP) and the scopes
+// granted at login (for Authorize).
+type storedToken struct {
+	ConnectorID string        `json:"connector_id"`
+	Token       *oauth2.Token `json:"token"`
+	Scopes      []string      `json:"scopes,omitempty"`
 }
 
-func (am *AuthModule) encryptToken(token *oauth2.Token) (*oauth2.Token, error) {
-	plaintext, err := json.Marshal(token)
+// encryptToken marshals token (with its issuing connector and granted
+// scopes) to JSON and seals it with AES-256-GCM, so the same blob can be
+// cached in memory and persisted to a TokenSink.
+func (am *AuthModule) encryptToken(connectorID string, token *oauth2.Token, scopes []string) ([]byte, error) {
+	plaintext, err := json.Marshal(storedToken{ConnectorID: connectorID, Token: token, Scopes: scopes})
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal token: %w", err)
 	}
@@ -237,22 +742,11 @@ func (am *AuthModule) encryptToken(token *oauth2.Token) (*oauth2.Token, error) {
 		return nil, fmt.Errorf("failed to generate nonce: %w", err)
 	}
 
-	ciphertext := gcm.Seal(nonce, nonce, plaintext, nil)
-	encryptedToken := &oauth2.Token{
-		AccessToken: base64.StdEncoding.EncodeToString(ciphertext),
-		TokenType:   token.TokenType,
-		Expiry:      token.Expiry,
-	}
-
-	return encryptedToken, nil
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
 }
 
-func (am *AuthModule) decryptToken(token *oauth2.Token) (*oauth2.Token, error) {
-	ciphertext, err := base64.StdEncoding.DecodeString(token.AccessToken)
-	if err != nil {
-		return nil, fmt.Errorf("failed to decode ciphertext: %w", err)
-	}
-
+// decryptToken reverses encryptToken.
+func (am *AuthModule) decryptToken(blob []byte) (*storedToken, error) {
 	block, err := aes.NewCipher(am.encryptionKey)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create cipher: %w", err)
@@ -264,20 +758,20 @@ func (am *AuthModule) decryptToken(token *oauth2.Token) (*oauth2.Token, error) {
 	}
 
 	nonceSize := gcm.NonceSize()
-	if len(ciphertext) < nonceSize {
+	if len(blob) < nonceSize {
 		return nil, errors.New("ciphertext too short")
 	}
 
-	nonce, ciphertext := ciphertext[:nonceSize], ciphertext[nonceSize:]
+	nonce, ciphertext := blob[:nonceSize], blob[nonceSize:]
 	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decrypt ciphertext: %w", err)
 	}
 
-	var decryptedToken oauth2.Token
-	if err := json.Unmarshal(plaintext, &decryptedToken); err != nil {
+	var decrypted storedToken
+	if err := json.Unmarshal(plaintext, &decrypted); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal decrypted token: %w", err)
 	}
 
-	return &decryptedToken, nil
+	return &decrypted, nil
 }
@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+
+	"golang.org/x/oauth2"
+)
+
+// Identity is the authenticated principal a Connector resolves from its
+// upstream IdP, independent of the wire protocol (OIDC claims, a SAML
+// assertion, a provider's own userinfo API, ...).
+type Identity struct {
+	Subject string
+	Email   string
+	// Scopes are the granted scopes AuthModule.Authorize checks required
+	// scopes against. Connectors that have no notion of scopes (GitHub,
+	// SAML) leave this empty.
+	Scopes []string
+}
+
+// Connector federates one upstream identity provider into AuthModule,
+// mirroring how dex composes many upstream IdPs behind one issuer. Each
+// configured connector is reachable at /auth/{id}/login.
+type Connector interface {
+	// ID is this connector's key in AuthModule's connector map and the
+	// {connector} path segment of its login route.
+	ID() string
+	// LoginURL returns the upstream authorization URL, parameterized with
+	// state so HandleCallback can recover which connector to dispatch to.
+	LoginURL(state string) (string, error)
+	// HandleCallback completes the upstream flow from an incoming callback
+	// request and returns the resolved identity plus the OAuth2 token
+	// GetAWSConfig later exchanges for AWS credentials.
+	HandleCallback(r *http.Request) (Identity, *oauth2.Token, error)
+	// Refresh exchanges an expired token for a new one.
+	Refresh(ctx context.Context, token *oauth2.Token) (*oauth2.Token, error)
+}
@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	devconfig "github.com/mrdankuta/aws-devops-api/config"
+	"golang.org/x/oauth2"
+)
+
+// defaultScopesClaim is used when a connector doesn't configure ScopesClaim.
+const defaultScopesClaim = "scope"
+
+// OIDCConnector is the generic connector used for any standards-compliant
+// OIDC provider, including "google" and "gitlab" connectors, whose
+// well-known issuers buildConnector defaults ProviderURL to.
+type OIDCConnector struct {
+	id          string
+	config      *oauth2.Config
+	verifier    *oidc.IDTokenVerifier
+	scopesClaim string
+
+	// nonces tracks the per-attempt OIDC nonce and PKCE code verifier, keyed
+	// by the CSRF state AuthModule generated for this login, so
+	// HandleCallback can verify the ID token wasn't replayed from a
+	// different flow and complete the PKCE exchange. An abandoned login
+	// never calls HandleCallback to remove its entry, so AuthModule's
+	// janitor calls sweepNonces to reap ones older than stateTTL — the same
+	// window the matching stateStore entry is held for.
+	nonces sync.Map
+}
+
+// oidcNonceEntry is the value stored in OIDCConnector.nonces for one
+// in-flight login.
+type oidcNonceEntry struct {
+	Nonce     string
+	Verifier  string
+	ExpiresAt time.Time
+}
+
+// NewOIDCConnector discovers cfg.ProviderURL's OIDC configuration and
+// builds a connector around it.
+func NewOIDCConnector(id string, cfg devconfig.ConnectorConfig) (*OIDCConnector, error) {
+	provider, err := oidc.NewProvider(context.Background(), cfg.ProviderURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OIDC provider: %w", err)
+	}
+
+	scopesClaim := cfg.ScopesClaim
+	if scopesClaim == "" {
+		scopesClaim = defaultScopesClaim
+	}
+
+	return &OIDCConnector{
+		id: id,
+		config: &oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			Endpoint:     provider.Endpoint(),
+			RedirectURL:  cfg.RedirectURL,
+			Scopes:       []string{oidc.ScopeOpenID, "profile", "email"},
+		},
+		verifier:    provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+		scopesClaim: scopesClaim,
+	}, nil
+}
+
+func (c *OIDCConnector) ID() string { return c.id }
+
+func (c *OIDCConnector) LoginURL(state string) (string, error) {
+	nonce, err := generateRandomNonce()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate oidc nonce: %w", err)
+	}
+	verifier := oauth2.GenerateVerifier()
+	c.nonces.Store(state, oidcNonceEntry{Nonce: nonce, Verifier: verifier, ExpiresAt: time.Now().Add(stateTTL)})
+	return c.config.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// sweepNonces deletes any nonce entry older than its ExpiresAt, so a login
+// that's abandoned before reaching HandleCallback doesn't leak an entry
+// here forever. AuthModule's janitor calls this via sweepConnectorNonces.
+func (c *OIDCConnector) sweepNonces(now time.Time) {
+	c.nonces.Range(func(key, value any) bool {
+		if entry, ok := value.(oidcNonceEntry); ok && now.After(entry.ExpiresAt) {
+			c.nonces.Delete(key)
+		}
+		return true
+	})
+}
+
+func (c *OIDCConnector) HandleCallback(r *http.Request) (Identity, *oauth2.Token, error) {
+	entryVal, ok := c.nonces.LoadAndDelete(r.FormValue("state"))
+	if !ok {
+		return Identity{}, nil, fmt.Errorf("unknown or expired state")
+	}
+	entry := entryVal.(oidcNonceEntry)
+	nonce := entry.Nonce
+
+	token, err := c.config.Exchange(r.Context(), r.FormValue("code"), oauth2.VerifierOption(entry.Verifier))
+	if err != nil {
+		return Identity{}, nil, fmt.Errorf("failed to exchange token: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return Identity{}, nil, fmt.Errorf("no id_token in token response")
+	}
+
+	idToken, err := c.verifier.Verify(r.Context(), rawIDToken)
+	if err != nil {
+		return Identity{}, nil, fmt.Errorf("failed to verify ID token: %w", err)
+	}
+
+	if idToken.Nonce != nonce {
+		return Identity{}, nil, fmt.Errorf("invalid nonce")
+	}
+
+	var claims struct {
+		Subject string `json:"sub"`
+		Email   string `json:"email"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return Identity{}, nil, fmt.Errorf("failed to extract claims: %w", err)
+	}
+
+	var rawClaims map[string]interface{}
+	if err := idToken.Claims(&rawClaims); err != nil {
+		return Identity{}, nil, fmt.Errorf("failed to extract raw claims: %w", err)
+	}
+
+	return Identity{
+		Subject: claims.Subject,
+		Email:   claims.Email,
+		Scopes:  extractScopes(rawClaims, c.scopesClaim),
+	}, token, nil
+}
+
+// extractScopes reads claimName out of claims, accepting either the
+// standard OAuth2 space-delimited "scope" string or a JSON array of strings
+// (as "groups"/"roles" claims typically are).
+func extractScopes(claims map[string]interface{}, claimName string) []string {
+	switch v := claims[claimName].(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		scopes := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				scopes = append(scopes, s)
+			}
+		}
+		return scopes
+	default:
+		return nil
+	}
+}
+
+func (c *OIDCConnector) Refresh(ctx context.Context, token *oauth2.Token) (*oauth2.Token, error) {
+	newToken, err := c.config.TokenSource(ctx, token).Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh token: %w", err)
+	}
+	return newToken, nil
+}
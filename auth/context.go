@@ -0,0 +1,22 @@
+package auth
+
+import "context"
+
+// sessionContextKey is the context.Context key ContextWithSessionID stores
+// under, unexported so only this package can set it.
+type sessionContextKey struct{}
+
+// ContextWithSessionID returns a copy of ctx carrying sessionID, so
+// GetAWSConfig and Authorize calls made further down a request's call chain
+// (e.g. from tasks/pipeline.go, well past the HTTP handler that read the
+// session cookie) can recover which browser session originated the call.
+func ContextWithSessionID(ctx context.Context, sessionID string) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, sessionID)
+}
+
+// SessionIDFromContext returns the session ID ContextWithSessionID attached
+// to ctx, if any.
+func SessionIDFromContext(ctx context.Context) (string, bool) {
+	sessionID, ok := ctx.Value(sessionContextKey{}).(string)
+	return sessionID, ok && sessionID != ""
+}
@@ -3,24 +3,13 @@ package auth
 import (
 	"crypto/rand"
 	"encoding/base64"
-	"net/http"
 
 	"github.com/gorilla/mux"
 )
 
 func (am *AuthModule) SetupRoutes(router *mux.Router) {
-	router.HandleFunc("/auth/login", am.handleLogin).Methods("GET")
-	router.HandleFunc("/auth/callback", am.HandleCallback).Methods("GET")
-}
-
-func (am *AuthModule) handleLogin(w http.ResponseWriter, r *http.Request) {
-	state, err := generateRandomState()
-	if err != nil {
-		http.Error(w, "Failed to generate state", http.StatusInternalServerError)
-		return
-	}
-
-	http.Redirect(w, r, am.GetAuthorizationURL(state), http.StatusFound)
+	router.HandleFunc("/auth/{connector}/login", am.StartOIDCFlow).Methods("GET")
+	router.HandleFunc("/auth/callback", am.HandleCallback).Methods("GET", "POST")
 }
 
 func generateRandomState() (string, error) {
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"time"
+)
+
+// janitor periodically reaps abandoned login states, unrefreshable cached
+// tokens, expired sessions, and any per-connector login state (e.g.
+// OIDCConnector's nonces), since nothing else ever scans any of those maps.
+// It runs until Close is called.
+func (am *AuthModule) janitor() {
+	ticker := time.NewTicker(janitorInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			am.sweepStateStore()
+			am.sweepTokenCache()
+			am.sweepSessionStore()
+			am.sweepConnectorNonces()
+		case <-am.stopJanitor:
+			return
+		}
+	}
+}
+
+// connectorNonceSweeper is implemented by connectors that track their own
+// per-flow state keyed by the CSRF state AuthModule generates (currently
+// only OIDCConnector). sweepConnectorNonces gives each one a chance to reap
+// its abandoned entries alongside stateStore/tokenCache/sessionStore.
+type connectorNonceSweeper interface {
+	sweepNonces(now time.Time)
+}
+
+// sweepConnectorNonces calls sweepNonces on every configured connector that
+// implements connectorNonceSweeper.
+func (am *AuthModule) sweepConnectorNonces() {
+	now := time.Now()
+	for _, connector := range am.connectors {
+		if sweeper, ok := connector.(connectorNonceSweeper); ok {
+			sweeper.sweepNonces(now)
+		}
+	}
+}
+
+// sweepStateStore deletes any login attempt state whose flow never
+// completed within stateTTL.
+func (am *AuthModule) sweepStateStore() {
+	now := time.Now()
+	am.stateStore.Range(func(key, value any) bool {
+		if entry, ok := value.(stateEntry); ok && now.After(entry.ExpiresAt) {
+			am.stateStore.Delete(key)
+			stateExpiredTotal.Inc()
+		}
+		return true
+	})
+}
+
+// sweepTokenCache evicts any cached token that is past its Expiry and
+// cannot be refreshed, either because it carries no refresh token or
+// because the upstream connector rejects the refresh attempt.
+func (am *AuthModule) sweepTokenCache() {
+	ctx := context.Background()
+
+	am.tokenCache.Range(func(key, value any) bool {
+		sessionID := key.(string)
+		entry := value.(tokenCacheEntry)
+
+		stored, err := am.decryptToken(entry.Blob)
+		if err != nil {
+			am.logger.Error("token_decrypt_failed", "session_id", sessionID, "error", err)
+			am.evictToken(ctx, sessionID)
+			return true
+		}
+
+		if stored.Token.Valid() {
+			return true
+		}
+
+		if stored.Token.RefreshToken == "" {
+			am.evictToken(ctx, sessionID)
+			return true
+		}
+
+		connector, ok := am.connectors[stored.ConnectorID]
+		if !ok {
+			am.evictToken(ctx, sessionID)
+			return true
+		}
+
+		newToken, err := connector.Refresh(ctx, stored.Token)
+		if err != nil {
+			tokenRefreshFailedTotal.Inc()
+			am.evictToken(ctx, sessionID)
+			return true
+		}
+
+		if err := am.storeToken(ctx, stored.ConnectorID, sessionID, newToken, stored.Scopes); err != nil {
+			am.logger.Error("janitor: failed to store refreshed token", "session_id", sessionID, "error", err)
+			return true
+		}
+		am.logger.Info("token_refreshed", "connector", stored.ConnectorID, "session_id", sessionID)
+		return true
+	})
+}
+
+// sweepSessionStore deletes any session cookie whose TTL has passed.
+func (am *AuthModule) sweepSessionStore() {
+	now := time.Now()
+	am.sessionStore.Range(func(key, value any) bool {
+		if entry, ok := value.(sessionEntry); ok && now.After(entry.ExpiresAt) {
+			am.sessionStore.Delete(key)
+			sessionExpiredTotal.Inc()
+		}
+		return true
+	})
+}
+
+// evictToken removes sessionID's token from the in-memory cache and, if
+// configured, the TokenSink.
+func (am *AuthModule) evictToken(ctx context.Context, sessionID string) {
+	am.tokenCache.Delete(sessionID)
+	tokenEvictedTotal.Inc()
+
+	if am.tokenSink != nil {
+		if err := am.tokenSink.Delete(ctx, sessionID); err != nil {
+			am.logger.Error("janitor: failed to delete persisted token", "session_id", sessionID, "error", err)
+		}
+	}
+}
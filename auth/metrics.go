@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	stateExpiredTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "aws_devops_api",
+		Subsystem: "auth",
+		Name:      "state_expired_total",
+		Help:      "Total number of login attempt states reaped by the janitor before their flow completed.",
+	})
+
+	tokenEvictedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "aws_devops_api",
+		Subsystem: "auth",
+		Name:      "token_evicted_total",
+		Help:      "Total number of cached tokens the janitor evicted as unrefreshable.",
+	})
+
+	tokenRefreshFailedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "aws_devops_api",
+		Subsystem: "auth",
+		Name:      "token_refresh_failed_total",
+		Help:      "Total number of background refresh attempts the janitor made that failed.",
+	})
+
+	sessionExpiredTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "aws_devops_api",
+		Subsystem: "auth",
+		Name:      "session_expired_total",
+		Help:      "Total number of session cookies reaped by the janitor after their TTL passed.",
+	})
+)
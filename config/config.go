@@ -8,20 +8,211 @@ import (
 )
 
 type Config struct {
-	OIDC  OIDCConfig   `yaml:"oidc"`
-	Slack SlackConfig  `yaml:"slack"`
-	Tasks []TaskConfig `yaml:"tasks"`
+	Auth   AuthConfig   `yaml:"auth"`
+	Slack  SlackConfig  `yaml:"slack"`
+	SMTP   SMTPConfig   `yaml:"smtp"`
+	Tasks  []TaskConfig `yaml:"tasks"`
+	Store  StoreConfig  `yaml:"store"`
+	Events EventsConfig `yaml:"events"`
+	Log    LogConfig    `yaml:"log"`
 }
 
-type OIDCConfig struct {
+// LogConfig selects the log/slog handler auth, iam, s3, and slack log
+// through. Format is "json" (default, for production log aggregation) or
+// "text" (for readable local development). Level is "debug", "info"
+// (default), "warn", or "error"; it can also be raised or lowered at
+// runtime via the /api/admin/log-level endpoint.
+type LogConfig struct {
+	Format string `yaml:"format"`
+	Level  string `yaml:"level"`
+}
+
+// SMTPConfig is the single SMTP server used by "smtp" task notifications.
+type SMTPConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+}
+
+// EventsConfig configures the CloudEvents sinks that task lifecycle events
+// are published to, plus how inbound task.execute events (POST /api/events)
+// are authenticated. Every field is optional; an unset sink is simply skipped.
+type EventsConfig struct {
+	Webhooks []WebhookSinkConfig `yaml:"webhooks"`
+	NATS     *NATSSinkConfig     `yaml:"nats"`
+	Kafka    *KafkaSinkConfig    `yaml:"kafka"`
+
+	// InboundSecret signs POST /api/events the same way WebhookSinkConfig's
+	// Secret signs outbound notifications: the sender HMAC-SHA256s the raw
+	// body with this secret and sends it hex-encoded in the X-Signature
+	// header as "sha256=<hex>". An external system (alert pipeline, GitOps
+	// controller) has no browser session to present, so this replaces
+	// session-cookie auth for that one route rather than being layered on
+	// top of it. An empty InboundSecret disables the route entirely.
+	InboundSecret string `yaml:"inbound_secret"`
+}
+
+type WebhookSinkConfig struct {
+	URL string `yaml:"url"`
+}
+
+type NATSSinkConfig struct {
+	URL     string `yaml:"url"`
+	Subject string `yaml:"subject"`
+}
+
+type KafkaSinkConfig struct {
+	Brokers []string `yaml:"brokers"`
+	Topic   string   `yaml:"topic"`
+}
+
+// NotificationConfig configures one sink a task's result is fanned out to,
+// in addition to the legacy TaskConfig.SlackChannel field. Type selects the
+// sink: "slack", "teams", "discord", "webhook", "pagerduty", or "smtp".
+// Target is sink-specific (a Slack channel, a Teams/Discord/webhook URL, a
+// PagerDuty routing key, or a comma-separated list of email addresses). On
+// restricts delivery to "success" and/or "failure"; empty means both.
+// Template is a Go text/template string rendered against notify.Data; an
+// empty Template uses the sink's default. Secret is only used by the
+// "webhook" type, as the HMAC-SHA256 signing key for the X-Signature header.
+type NotificationConfig struct {
+	Type     string   `yaml:"type"`
+	Target   string   `yaml:"target"`
+	On       []string `yaml:"on"`
+	Template string   `yaml:"template"`
+	Secret   string   `yaml:"secret"`
+}
+
+// StoreConfig selects the tasks.Store backend. Type is "sqlite" (default,
+// DSN is a file path or ":memory:") or "postgres" (DSN is a connection string).
+type StoreConfig struct {
+	Type string `yaml:"type"`
+	DSN  string `yaml:"dsn"`
+}
+
+// AuthConfig configures authentication: the set of upstream identity
+// providers tokens can come from, plus how those tokens are encrypted and
+// persisted.
+type AuthConfig struct {
+	// Connectors is the set of upstream IdPs AuthModule federates, each
+	// reachable at /auth/{id}/login.
+	Connectors []ConnectorConfig `yaml:"connectors"`
+
+	// TokenEncryption selects the stable source the AES key used to encrypt
+	// cached tokens is loaded from, instead of a fresh key per boot.
+	TokenEncryption TokenEncryptionConfig `yaml:"token_encryption"`
+	// TokenSink persists encrypted tokens out-of-band so they survive a
+	// restart. An unset Type keeps the legacy in-memory-only behavior.
+	TokenSink TokenSinkConfig `yaml:"token_sink"`
+
+	// Session controls the cookie issued after a successful callback.
+	Session SessionConfig `yaml:"session"`
+
+	// SystemAccount grants scopes to scheduled and other non-interactive
+	// task runs, which have no browser session to resolve a caller identity
+	// or granted scopes from.
+	SystemAccount SystemAccountConfig `yaml:"system_account"`
+}
+
+// SystemAccountConfig configures the identity scheduled cron firings and
+// inbound task.execute CloudEvents run under (see AuthModule.SystemSessionID).
+// Unlike a browser session, it never resolves an OIDC token: GetAWSConfig
+// assumes a step's role ARN directly from the process's own ambient AWS
+// identity (an EC2 instance role, ECS task role, or IRSA pod role), the
+// same way any other AWS automation running on that host would. Scopes
+// lists what RequiredScopes a step may demand before the system account is
+// allowed to run it; an empty Scopes denies every scoped command, so a
+// fresh deployment doesn't silently grant scheduled tasks more access than
+// an operator opted into.
+type SystemAccountConfig struct {
+	Scopes []string `yaml:"scopes"`
+}
+
+// SessionConfig controls the cookie AuthModule issues once a callback
+// completes. TTL is parsed with time.ParseDuration and defaults to 24h if
+// unset or invalid. Insecure drops the cookies' Secure attribute, for local
+// development over plain HTTP; it must stay false (the default) behind TLS.
+type SessionConfig struct {
+	TTL      string `yaml:"ttl"`
+	Insecure bool   `yaml:"insecure"`
+}
+
+// ConnectorConfig configures one upstream identity provider. Type selects
+// the connector implementation:
+//   - "oidc": a generic OIDC-discovery provider; ProviderURL is required.
+//   - "google": OIDC discovery against accounts.google.com; ProviderURL
+//     defaults if unset.
+//   - "gitlab": OIDC discovery against gitlab.com; ProviderURL defaults if
+//     unset.
+//   - "github": GitHub's plain OAuth2 flow (no OIDC discovery); identity
+//     comes from GitHub's REST user API instead of an ID token.
+//   - "saml": a minimal SAML 2.0 service provider; SAMLSSOURL, SAMLEntityID,
+//     and SAMLACSURL are required, the OAuth2/OIDC fields are unused. It does
+//     not verify assertion signatures, so SAMLAllowUnverified must also be
+//     set to true.
+type ConnectorConfig struct {
+	ID           string `yaml:"id"`
+	Type         string `yaml:"type"`
 	ProviderURL  string `yaml:"provider_url"`
 	ClientID     string `yaml:"client_id"`
 	ClientSecret string `yaml:"client_secret"`
 	RedirectURL  string `yaml:"redirect_url"`
+
+	// SAMLSSOURL, SAMLEntityID, and SAMLACSURL are only used by the "saml"
+	// connector type.
+	SAMLSSOURL   string `yaml:"saml_sso_url"`
+	SAMLEntityID string `yaml:"saml_entity_id"`
+	SAMLACSURL   string `yaml:"saml_acs_url"`
+
+	// SAMLAllowUnverified must be explicitly set to true to use the "saml"
+	// connector type. It does not verify the IdP's assertion signature, so
+	// anyone who can POST to the ACS URL can forge an assertion claiming to
+	// be any user; only set this for a trusted deployment that understands
+	// and accepts that risk. Defaults to false (connector construction
+	// fails closed).
+	SAMLAllowUnverified bool `yaml:"saml_allow_unverified"`
+
+	// ScopesClaim names the ID token claim AuthModule.Authorize's granted
+	// scopes are read from, e.g. "groups", "roles", or "scope" (the OAuth2
+	// convention, and the default if unset). Only used by OIDC-family
+	// connector types ("oidc", "google", "gitlab").
+	ScopesClaim string `yaml:"scopes_claim"`
+}
+
+// TokenEncryptionConfig selects where the AES-256 key that encrypts cached
+// OIDC tokens comes from. Exactly one of KeyEnv, KeyFile, or PassphraseEnv
+// should be set; if none are, a random key is generated per boot as before,
+// which means every restart forces re-authentication.
+type TokenEncryptionConfig struct {
+	// KeyEnv names an env var holding a base64-encoded 32-byte key.
+	KeyEnv string `yaml:"key_env"`
+	// KeyFile is a path to a file holding a base64-encoded 32-byte key, e.g.
+	// one decrypted from KMS by the deployment pipeline before the process starts.
+	KeyFile string `yaml:"key_file"`
+	// PassphraseEnv names an env var holding a passphrase; the key is
+	// derived from it with HKDF-SHA256.
+	PassphraseEnv string `yaml:"passphrase_env"`
+}
+
+// TokenSinkConfig selects the auth.TokenSink backend that persists encrypted
+// tokens across restarts. Type is "file" or "k8s_secret"; an empty Type
+// disables persistence and keeps tokens in memory only.
+type TokenSinkConfig struct {
+	Type string `yaml:"type"`
+	// Dir is the directory the "file" backend writes one blob per account
+	// to.
+	Dir string `yaml:"dir"`
+	// Namespace and NamePrefix are used by the "k8s_secret" backend, which
+	// writes one Secret per account named "<NamePrefix><sanitized accountID>".
+	Namespace  string `yaml:"namespace"`
+	NamePrefix string `yaml:"name_prefix"`
 }
 
 type SlackConfig struct {
-	Token string `yaml:"token"`
+	Token             string `yaml:"token"`
+	DeadLetterChannel string `yaml:"dead_letter_channel"`
 }
 
 type TaskConfig struct {
@@ -31,6 +222,39 @@ type TaskConfig struct {
 	Command      string   `yaml:"command"`
 	Schedule     string   `yaml:"schedule"`
 	SlackChannel string   `yaml:"slack_channel"`
+
+	// Steps turns the task into a multi-step pipeline executed as a DAG
+	// instead of a single Service/Command call. When empty, Service/Command
+	// above run as the task's one implicit step.
+	Steps []StepConfig `yaml:"steps"`
+
+	// Notifications fans a task's result out to any number of sinks beyond
+	// the legacy SlackChannel field above.
+	Notifications []NotificationConfig `yaml:"notifications"`
+
+	// Timeout bounds a single execution attempt, parsed with time.ParseDuration (e.g. "30s").
+	Timeout string `yaml:"timeout"`
+	// MaxRetries is the number of retries attempted after the first failure.
+	MaxRetries int `yaml:"max_retries"`
+	// RetryDelay is the base delay between attempts, parsed with time.ParseDuration.
+	RetryDelay string `yaml:"retry_delay"`
+	// RetryBackoff is "linear" or "exponential". Defaults to "linear".
+	RetryBackoff string `yaml:"retry_backoff"`
+}
+
+// StepConfig is one node of a task pipeline's DAG. DependsOn names sibling
+// steps that must finish before this one starts. When controls whether this
+// step still runs if a dependency failed: "on_success" (default),
+// "on_failure", or "always". A step's Command may reference
+// ${steps.NAME.result} to consume the captured result of any step named in
+// DependsOn.
+type StepConfig struct {
+	Name        string   `yaml:"name"`
+	Service     string   `yaml:"service"`
+	Command     string   `yaml:"command"`
+	AWSAccounts []string `yaml:"aws_accounts"`
+	DependsOn   []string `yaml:"depends_on"`
+	When        string   `yaml:"when"`
 }
 
 func Load(filename string) (*Config, error) {
@@ -45,9 +269,9 @@ func Load(filename string) (*Config, error) {
 	}
 
 	fmt.Printf("Loaded configuration with %d tasks\n", len(config.Tasks))
-    for i, task := range config.Tasks {
-        fmt.Printf("Task %d: Name=%s, Service=%s\n", i, task.Name, task.Service)
-    }
+	for i, task := range config.Tasks {
+		fmt.Printf("Task %d: Name=%s, Service=%s\n", i, task.Name, task.Service)
+	}
 
 	return &config, nil
 }
@@ -0,0 +1,53 @@
+// Package events publishes CloudEvents (spec 1.0, JSON format) for task
+// lifecycle transitions and accepts inbound CloudEvents to trigger tasks
+// out-of-band from cron.
+package events
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Source identifies this service as the CloudEvents "source" attribute.
+const Source = "github.com/mrdankuta/aws-devops-api"
+
+// Event types for task lifecycle transitions and the inbound trigger event.
+const (
+	TypeTaskScheduled    = "com.aws-devops-api.task.scheduled"
+	TypeTaskStarted      = "com.aws-devops-api.task.started"
+	TypeTaskSucceeded    = "com.aws-devops-api.task.succeeded"
+	TypeTaskFailed       = "com.aws-devops-api.task.failed"
+	TypeTaskRetried      = "com.aws-devops-api.task.retried"
+	TypeTaskDeadlettered = "com.aws-devops-api.task.deadlettered"
+	TypeTaskExecute      = "com.aws-devops-api.task.execute"
+)
+
+// Event is a CloudEvent in structured (JSON) mode, per the 1.0 spec.
+type Event struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// TaskEventData is the `data` payload carried by task lifecycle events.
+type TaskEventData struct {
+	TaskID      string   `json:"taskId"`
+	TaskName    string   `json:"taskName"`
+	AWSAccounts []string `json:"awsAccounts"`
+	Service     string   `json:"service"`
+	Command     string   `json:"command"`
+	Attempt     int      `json:"attempt,omitempty"`
+	DurationMS  int64    `json:"durationMs,omitempty"`
+	Result      string   `json:"result,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// TaskExecuteData is the `data` payload expected on an inbound
+// com.aws-devops-api.task.execute event.
+type TaskExecuteData struct {
+	TaskID string `json:"taskId"`
+}
@@ -0,0 +1,46 @@
+package events
+
+import (
+	"context"
+	"fmt"
+
+	kafka "github.com/segmentio/kafka-go"
+)
+
+// KafkaSink publishes events to a Kafka topic.
+type KafkaSink struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaSink returns a sink that writes to topic across brokers.
+func NewKafkaSink(brokers []string, topic string) *KafkaSink {
+	return &KafkaSink{
+		writer: &kafka.Writer{
+			Addr:     kafka.TCP(brokers...),
+			Topic:    topic,
+			Balancer: &kafka.LeastBytes{},
+		},
+	}
+}
+
+func (k *KafkaSink) Publish(ctx context.Context, event Event) error {
+	msg := kafka.Message{
+		Key:   []byte(event.ID),
+		Value: event.Data,
+		Headers: []kafka.Header{
+			{Key: "ce_id", Value: []byte(event.ID)},
+			{Key: "ce_source", Value: []byte(event.Source)},
+			{Key: "ce_type", Value: []byte(event.Type)},
+			{Key: "ce_specversion", Value: []byte(event.SpecVersion)},
+		},
+	}
+
+	if err := k.writer.WriteMessages(ctx, msg); err != nil {
+		return fmt.Errorf("failed to publish event to Kafka topic %s: %w", k.writer.Topic, err)
+	}
+	return nil
+}
+
+func (k *KafkaSink) Close() error {
+	return k.writer.Close()
+}
@@ -0,0 +1,35 @@
+package events
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSSink publishes events to a NATS subject.
+type NATSSink struct {
+	conn    *nats.Conn
+	subject string
+}
+
+// NewNATSSink connects to a NATS server at url and returns a sink that
+// publishes to subject.
+func NewNATSSink(url, subject string) (*NATSSink, error) {
+	conn, err := nats.Connect(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS at %s: %w", url, err)
+	}
+	return &NATSSink{conn: conn, subject: subject}, nil
+}
+
+func (n *NATSSink) Publish(ctx context.Context, event Event) error {
+	if err := n.conn.Publish(n.subject, event.Data); err != nil {
+		return fmt.Errorf("failed to publish event to NATS subject %s: %w", n.subject, err)
+	}
+	return nil
+}
+
+func (n *NATSSink) Close() {
+	n.conn.Close()
+}
@@ -0,0 +1,62 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Sink delivers a CloudEvent to one downstream system (webhook, NATS, Kafka, ...).
+type Sink interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// Publisher fans a task lifecycle event out to every configured Sink.
+type Publisher struct {
+	sinks []Sink
+}
+
+// NewPublisher returns a Publisher that fans out to sinks. A nil/empty sink
+// list is valid and makes Publish a no-op, so callers can wire a Publisher
+// unconditionally even when no sinks are configured.
+func NewPublisher(sinks ...Sink) *Publisher {
+	return &Publisher{sinks: sinks}
+}
+
+// Publish builds a CloudEvent of the given type around data and delivers it
+// to every sink, collecting (but not short-circuiting on) individual failures.
+func (p *Publisher) Publish(ctx context.Context, eventType string, data any) error {
+	if p == nil || len(p.sinks) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event data: %w", err)
+	}
+
+	event := Event{
+		SpecVersion:     "1.0",
+		ID:              uuid.New().String(),
+		Source:          Source,
+		Type:            eventType,
+		Time:            time.Now(),
+		DataContentType: "application/json",
+		Data:            payload,
+	}
+
+	var errs []error
+	for _, sink := range p.sinks {
+		if err := sink.Publish(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to publish %s to %d sink(s): %v", eventType, len(errs), errs)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+// Package logging builds the *slog.Logger shared by auth, iam, s3, and
+// slack, so every package logs through the same handler and level instead
+// of each picking its own.
+package logging
+
+import (
+	"log/slog"
+	"os"
+
+	"github.com/mrdankuta/aws-devops-api/config"
+)
+
+// New builds a *slog.Logger per cfg and returns the slog.LevelVar backing
+// its level, so callers can adjust verbosity at runtime (e.g. from an
+// admin endpoint) without rebuilding the logger. Format "text" selects a
+// human-readable handler for local development; anything else (including
+// unset) selects the JSON handler used in production.
+func New(cfg config.LogConfig) (*slog.Logger, *slog.LevelVar) {
+	level := &slog.LevelVar{}
+	level.Set(parseLevel(cfg.Level))
+
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	if cfg.Format == "text" {
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	} else {
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	}
+
+	return slog.New(handler), level
+}
+
+func parseLevel(level string) slog.Level {
+	switch level {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
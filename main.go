@@ -13,8 +13,16 @@ import (
 	"github.com/mrdankuta/aws-devops-api/api"
 	"github.com/mrdankuta/aws-devops-api/auth"
 	"github.com/mrdankuta/aws-devops-api/config"
+	"github.com/mrdankuta/aws-devops-api/events"
+	"github.com/mrdankuta/aws-devops-api/logging"
+	"github.com/mrdankuta/aws-devops-api/notify"
 	"github.com/mrdankuta/aws-devops-api/slack"
+	"github.com/mrdankuta/aws-devops-api/store/postgres"
+	"github.com/mrdankuta/aws-devops-api/store/sqlite"
 	"github.com/mrdankuta/aws-devops-api/tasks"
+	"github.com/mrdankuta/aws-devops-api/tokensink/file"
+	"github.com/mrdankuta/aws-devops-api/tokensink/k8ssecret"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
 )
 
@@ -34,25 +42,48 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	// Build the structured logger shared by auth, iam, s3, and slack, with a
+	// level that can be adjusted at runtime via /api/admin/log-level.
+	appLogger, logLevel := logging.New(cfg.Log)
+
+	// Initialize token sink (persists encrypted tokens across restarts)
+	tokenSink, err := newTokenSink(&cfg.Auth.TokenSink)
+	if err != nil {
+		log.Fatalf("Failed to initialize token sink: %v", err)
+	}
+
 	// Initialize authentication module
-	authModule, err := auth.NewAuthModule(&cfg.OIDC)
+	authModule, err := auth.NewAuthModule(cfg.Auth.Connectors, cfg.Auth.TokenEncryption, tokenSink, appLogger, cfg.Auth.Session, cfg.Auth.SystemAccount)
 	if err != nil {
 		log.Fatalf("Failed to create auth module: %v", err)
 	}
 
+	// Initialize Slack client
+	slackClient := slack.NewClient(cfg.Slack.Token, appLogger)
+
+	// Initialize task store
+	taskStore, err := newTaskStore(&cfg.Store)
+	if err != nil {
+		log.Fatalf("Failed to initialize task store: %v", err)
+	}
+
+	// Initialize CloudEvents publisher
+	publisher := newEventPublisher(&cfg.Events)
+
+	// Initialize notification fan-out registry
+	notifier := notify.NewRegistry(slackClient, cfg.SMTP)
+
 	// Initialize task manager
-	taskManager := tasks.NewTaskManager(&cfg.Tasks, authModule)
+	taskManager := tasks.NewTaskManager(&cfg.Tasks, authModule, slackClient, &cfg.Slack, taskStore, publisher, notifier, appLogger)
 	fmt.Printf("TaskManager created with %d tasks\n", len(taskManager.GetAllTasks()))
 
-	// Initialize Slack client
-	slackClient := slack.NewClient(cfg.Slack.Token)
-
 	// Initialize API
-	api := api.NewAPI(cfg, authModule, taskManager)
+	api := api.NewAPI(cfg, authModule, taskManager, logLevel)
 
 	// Set up HTTP router
 	router := mux.NewRouter()
 	api.SetupRoutes(router)
+	router.Handle("/metrics", promhttp.Handler()).Methods("GET")
 	// authModule.SetupRoutes(router)
 	// apiHandler.SetupRoutes(router)
 
@@ -72,14 +103,6 @@ func main() {
 
 	log.Info("Starting application")
 
-	// Start task execution loop
-	go func() {
-		for {
-			executeTasks(taskManager, slackClient)
-			time.Sleep(1 * time.Minute) // Check every minute
-		}
-	}()
-
 	// Wait for interrupt signal to gracefully shut down the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -91,42 +114,69 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
+	authModule.Close()
 
 	log.Println("Server exiting")
 }
 
-func executeTasks(tm *tasks.TaskManager, sc *slack.Client) {
-	for _, task := range tm.GetDueTasks() {
-		go func(t tasks.Task) {
-			log.WithFields(logrus.Fields{
-				"taskID":   t.ID,
-				"taskName": t.Name,
-			}).Debug("Executing task")
-
-			result, err := t.Execute()
-			if err != nil {
-				log.WithFields(logrus.Fields{
-					"taskID":   t.ID,
-					"taskName": t.Name,
-					"error":    err,
-				}).Error("Error executing task")
-				return
-			}
-
-			log.WithFields(logrus.Fields{
-				"taskID":   t.ID,
-				"taskName": t.Name,
-				"result":   result,
-			}).Info("Task executed successfully")
-
-			if err := sc.PostMessage(t.SlackChannel, result); err != nil {
-				log.WithFields(logrus.Fields{
-					"taskID":       t.ID,
-					"taskName":     t.Name,
-					"slackChannel": t.SlackChannel,
-					"error":        err,
-				}).Error("Error posting to Slack")
-			}
-		}(task)
+// newTaskStore builds the tasks.Store backend selected by cfg.Type, defaulting
+// to a local SQLite file so the app runs with zero external infrastructure.
+func newTaskStore(cfg *config.StoreConfig) (tasks.Store, error) {
+	switch cfg.Type {
+	case "postgres":
+		return postgres.Open(cfg.DSN)
+	case "sqlite", "":
+		dsn := cfg.DSN
+		if dsn == "" {
+			dsn = "tasks.db"
+		}
+		return sqlite.Open(dsn)
+	default:
+		return nil, fmt.Errorf("unknown store type: %s", cfg.Type)
+	}
+}
+
+// newTokenSink builds the auth.TokenSink backend selected by cfg.Type. An
+// empty Type returns a nil sink, which keeps tokens in memory only, same as
+// before this was configurable.
+func newTokenSink(cfg *config.TokenSinkConfig) (auth.TokenSink, error) {
+	switch cfg.Type {
+	case "file":
+		dir := cfg.Dir
+		if dir == "" {
+			dir = "tokens"
+		}
+		return file.Open(dir)
+	case "k8s_secret":
+		return k8ssecret.Open(cfg.Namespace, cfg.NamePrefix)
+	case "":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown token sink type: %s", cfg.Type)
+	}
+}
+
+// newEventPublisher builds a CloudEvents publisher from every sink configured
+// under the events: block. Sinks that fail to initialize are logged and skipped.
+func newEventPublisher(cfg *config.EventsConfig) *events.Publisher {
+	var sinks []events.Sink
+
+	for _, wh := range cfg.Webhooks {
+		sinks = append(sinks, events.NewWebhookSink(wh.URL))
+	}
+
+	if cfg.NATS != nil {
+		natsSink, err := events.NewNATSSink(cfg.NATS.URL, cfg.NATS.Subject)
+		if err != nil {
+			log.Printf("Failed to initialize NATS event sink: %v", err)
+		} else {
+			sinks = append(sinks, natsSink)
+		}
+	}
+
+	if cfg.Kafka != nil {
+		sinks = append(sinks, events.NewKafkaSink(cfg.Kafka.Brokers, cfg.Kafka.Topic))
 	}
+
+	return events.NewPublisher(sinks...)
 }
@@ -0,0 +1,42 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// DiscordSink posts the rendered message to a Discord incoming webhook.
+type DiscordSink struct {
+	httpClient *http.Client
+}
+
+type discordMessage struct {
+	Content string `json:"content"`
+}
+
+func (d *DiscordSink) Send(ctx context.Context, target, message string, data Data) error {
+	body, err := json.Marshal(discordMessage{Content: message})
+	if err != nil {
+		return fmt.Errorf("failed to marshal Discord message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build Discord request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post message to Discord webhook %s: %w", target, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("Discord webhook %s returned status %d", target, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+// Package notify fans a task's execution result out to operator-configured
+// sinks (Slack, Teams, Discord, generic webhooks, PagerDuty, email) beyond
+// the single Slack channel a task can already post to. Each sink renders a
+// Go text/template message from Data before delivery.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"text/template"
+	"time"
+
+	"github.com/mrdankuta/aws-devops-api/config"
+	"github.com/mrdankuta/aws-devops-api/slack"
+)
+
+// Data is the payload a NotificationConfig.Template is rendered against.
+type Data struct {
+	TaskID   string
+	TaskName string
+	Service  string
+	Command  string
+	Success  bool
+	Result   string
+	Error    string
+}
+
+// defaultTemplate is used by any notification with no Template configured.
+const defaultTemplate = `Task {{.TaskName}} {{if .Success}}succeeded{{else}}failed{{end}}: {{if .Success}}{{.Result}}{{else}}{{.Error}}{{end}}`
+
+// Sink delivers a rendered message to one downstream system.
+type Sink interface {
+	Send(ctx context.Context, target, message string, data Data) error
+}
+
+// Registry builds the Sink for each configured notification type and
+// dispatches a task's result to every sink that applies to the outcome.
+type Registry struct {
+	slackClient *slack.Client
+	smtpConfig  config.SMTPConfig
+	httpClient  *http.Client
+}
+
+// NewRegistry returns a Registry. slackClient may be nil if Slack isn't
+// configured; "slack" notifications then fail with a clear error rather
+// than panicking.
+func NewRegistry(slackClient *slack.Client, smtpConfig config.SMTPConfig) *Registry {
+	return &Registry{
+		slackClient: slackClient,
+		smtpConfig:  smtpConfig,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Dispatch renders and delivers data to every notification in notifications
+// whose On list includes the outcome data.Success represents. Individual
+// sink failures are collected rather than short-circuiting delivery to the
+// remaining sinks.
+func (r *Registry) Dispatch(ctx context.Context, notifications []config.NotificationConfig, data Data) error {
+	if r == nil {
+		return nil
+	}
+
+	outcome := "failure"
+	if data.Success {
+		outcome = "success"
+	}
+
+	var errs []error
+	for _, n := range notifications {
+		if !appliesTo(n.On, outcome) {
+			continue
+		}
+
+		sink, err := r.sinkFor(n)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		message, err := render(n.Template, data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s notification %s: %w", n.Type, n.Target, err))
+			continue
+		}
+
+		if err := sink.Send(ctx, n.Target, message, data); err != nil {
+			errs = append(errs, fmt.Errorf("%s notification %s: %w", n.Type, n.Target, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to dispatch %d of %d notification(s): %v", len(errs), len(notifications), errs)
+	}
+	return nil
+}
+
+// sinkFor returns the Sink for a notification's type, built with whatever
+// shared client/config (and per-notification secret) that type needs.
+func (r *Registry) sinkFor(n config.NotificationConfig) (Sink, error) {
+	switch n.Type {
+	case "slack":
+		if r.slackClient == nil {
+			return nil, fmt.Errorf("slack notifications require slack.token to be configured")
+		}
+		return &SlackSink{client: r.slackClient}, nil
+	case "teams":
+		return &TeamsSink{httpClient: r.httpClient}, nil
+	case "discord":
+		return &DiscordSink{httpClient: r.httpClient}, nil
+	case "webhook":
+		return &WebhookSink{httpClient: r.httpClient, secret: n.Secret}, nil
+	case "pagerduty":
+		return &PagerDutySink{httpClient: r.httpClient}, nil
+	case "smtp":
+		return &SMTPSink{cfg: r.smtpConfig}, nil
+	default:
+		return nil, fmt.Errorf("unknown notification type: %s", n.Type)
+	}
+}
+
+// appliesTo reports whether a notification configured with the given "on"
+// list should fire for outcome. An empty list applies to every outcome.
+func appliesTo(on []string, outcome string) bool {
+	if len(on) == 0 {
+		return true
+	}
+	for _, o := range on {
+		if o == outcome {
+			return true
+		}
+	}
+	return false
+}
+
+// render executes tmplStr (or defaultTemplate, if empty) against data.
+func render(tmplStr string, data Data) (string, error) {
+	if tmplStr == "" {
+		tmplStr = defaultTemplate
+	}
+
+	t, err := template.New("notification").Parse(tmplStr)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,73 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// pagerDutyEventsURL is the PagerDuty Events API v2 endpoint.
+const pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDutySink triggers a PagerDuty incident via the Events API v2. Target
+// is the integration's routing key. Intended for "on: [failure]"
+// notifications so on-call gets paged on task failure, e.g. iam policy drift.
+type PagerDutySink struct {
+	httpClient *http.Client
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string                `json:"routing_key"`
+	EventAction string                `json:"event_action"`
+	DedupKey    string                `json:"dedup_key,omitempty"`
+	Payload     pagerDutyEventPayload `json:"payload"`
+}
+
+type pagerDutyEventPayload struct {
+	Summary       string `json:"summary"`
+	Source        string `json:"source"`
+	Severity      string `json:"severity"`
+	CustomDetails any    `json:"custom_details,omitempty"`
+}
+
+func (p *PagerDutySink) Send(ctx context.Context, target, message string, data Data) error {
+	event := pagerDutyEvent{
+		RoutingKey:  target,
+		EventAction: "trigger",
+		DedupKey:    data.TaskID,
+		Payload: pagerDutyEventPayload{
+			Summary:  message,
+			Source:   data.TaskName,
+			Severity: "error",
+			CustomDetails: map[string]string{
+				"service": data.Service,
+				"command": data.Command,
+				"error":   data.Error,
+			},
+		},
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal PagerDuty event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, pagerDutyEventsURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build PagerDuty request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to trigger PagerDuty event: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("PagerDuty Events API returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/mrdankuta/aws-devops-api/slack"
+)
+
+// SlackSink posts the rendered message to a Slack channel via the shared
+// slack.Client, same as the legacy TaskConfig.SlackChannel field.
+type SlackSink struct {
+	client *slack.Client
+}
+
+func (s *SlackSink) Send(ctx context.Context, target, message string, data Data) error {
+	return s.client.PostMessage(target, message)
+}
@@ -0,0 +1,42 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+	"strings"
+
+	"github.com/mrdankuta/aws-devops-api/config"
+)
+
+// SMTPSink emails the rendered message through the single SMTP server
+// configured under config.SMTPConfig. Target is a comma-separated list of
+// recipient addresses.
+type SMTPSink struct {
+	cfg config.SMTPConfig
+}
+
+func (s *SMTPSink) Send(ctx context.Context, target, message string, data Data) error {
+	if s.cfg.Host == "" {
+		return fmt.Errorf("smtp notifications require smtp.host to be configured")
+	}
+
+	recipients := strings.Split(target, ",")
+	for i, r := range recipients {
+		recipients[i] = strings.TrimSpace(r)
+	}
+
+	subject := fmt.Sprintf("Task %s %s", data.TaskName, map[bool]string{true: "succeeded", false: "failed"}[data.Success])
+	body := fmt.Sprintf("Subject: %s\r\n\r\n%s\r\n", subject, message)
+
+	addr := fmt.Sprintf("%s:%d", s.cfg.Host, s.cfg.Port)
+	var auth smtp.Auth
+	if s.cfg.Username != "" {
+		auth = smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+	}
+
+	if err := smtp.SendMail(addr, auth, s.cfg.From, recipients, []byte(body)); err != nil {
+		return fmt.Errorf("failed to send email notification to %s: %w", target, err)
+	}
+	return nil
+}
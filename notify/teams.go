@@ -0,0 +1,43 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// TeamsSink posts the rendered message to a Microsoft Teams incoming
+// webhook as a plain-text message card.
+type TeamsSink struct {
+	httpClient *http.Client
+}
+
+type teamsMessage struct {
+	Text string `json:"text"`
+}
+
+func (t *TeamsSink) Send(ctx context.Context, target, message string, data Data) error {
+	body, err := json.Marshal(teamsMessage{Text: message})
+	if err != nil {
+		return fmt.Errorf("failed to marshal Teams message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build Teams request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post message to Teams webhook %s: %w", target, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("Teams webhook %s returned status %d", target, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// WebhookSink POSTs a JSON body carrying the rendered message and task data
+// to a generic endpoint. When the notification configures a Secret, the
+// body is signed with HMAC-SHA256 and carried in the X-Signature header as
+// "sha256=<hex>", the same convention used by GitHub/Stripe-style webhooks.
+type WebhookSink struct {
+	httpClient *http.Client
+	secret     string
+}
+
+type webhookPayload struct {
+	TaskID   string `json:"taskId"`
+	TaskName string `json:"taskName"`
+	Success  bool   `json:"success"`
+	Message  string `json:"message"`
+	Result   string `json:"result,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+func (w *WebhookSink) Send(ctx context.Context, target, message string, data Data) error {
+	body, err := json.Marshal(webhookPayload{
+		TaskID:   data.TaskID,
+		TaskName: data.TaskName,
+		Success:  data.Success,
+		Message:  message,
+		Result:   data.Result,
+		Error:    data.Error,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.secret != "" {
+		req.Header.Set("X-Signature", "sha256="+sign(w.secret, body))
+	}
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post notification to webhook %s: %w", target, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s returned status %d", target, resp.StatusCode)
+	}
+	return nil
+}
+
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -3,28 +3,80 @@ package iam
 import (
 	"context"
 	"fmt"
+	"log/slog"
 
 	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/google/uuid"
 	"github.com/mrdankuta/aws-devops-api/auth"
 )
 
-func NewCommand(command string, accounts []string, authModule *auth.AuthModule) func() (string, error) {
-	return func() (string, error) {
+// commandRoles maps each command to the default IAM role assumed while
+// running it, so a command is bound to no more access than it needs rather
+// than every command sharing one hard-coded role.
+var commandRoles = map[string]string{
+	"list_iam_users": "ReadOnlyRole",
+}
+
+// adminScope is the granted scope that elevates a command to AdminRole
+// instead of its default role in commandRoles, e.g. for an operator who
+// needs to act on the IAM users a read-only audit only reports on.
+const adminScope = "iam:admin"
+
+// selectRole picks the role ARN-fragment to assume for command, given the
+// scopes the caller's token was granted. adminScope takes precedence over
+// command's default role, so GetAWSConfig impersonates no more access than
+// the caller actually has.
+func selectRole(command string, grantedScopes []string) string {
+	for _, scope := range grantedScopes {
+		if scope == adminScope {
+			return "AdminRole"
+		}
+	}
+	if role := commandRoles[command]; role != "" {
+		return role
+	}
+	return "ReadOnlyRole"
+}
+
+// RequiredScopes returns the OIDC scope AuthModule.Authorize must see
+// granted before command may run.
+func RequiredScopes(command string) ([]string, error) {
+	switch command {
+	case "list_iam_users":
+		return []string{"iam:list_users"}, nil
+	default:
+		return nil, fmt.Errorf("unknown IAM command: %s", command)
+	}
+}
+
+// NewCommand returns command's executor. sessionID identifies the caller
+// whose AWS credentials GetAWSConfig should mint, and grantedScopes carries
+// the scopes that caller's token was granted (see auth.AuthModule.Authorize),
+// used by selectRole to pick the role assumed in each account.
+func NewCommand(command string, sessionID string, accounts []string, grantedScopes []string, authModule *auth.AuthModule, logger *slog.Logger) func(ctx context.Context) (string, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return func(ctx context.Context) (string, error) {
 		switch command {
 		case "list_iam_users":
-			return listIAMUsers(accounts, authModule)
+			return listIAMUsers(ctx, sessionID, accounts, grantedScopes, authModule, logger)
 		default:
 			return "", fmt.Errorf("unknown IAM command: %s", command)
 		}
 	}
 }
 
-func listIAMUsers(accounts []string, authModule *auth.AuthModule) (string, error) {
-	ctx := context.Background()
+func listIAMUsers(ctx context.Context, sessionID string, accounts []string, grantedScopes []string, authModule *auth.AuthModule, logger *slog.Logger) (string, error) {
 	iamUsers := make(map[string][]string)
+	requestID := uuid.New().String()
 
 	for _, account := range accounts {
-		cfg, err := authModule.GetAWSConfig(ctx, account, fmt.Sprintf("arn:aws:iam::%s:role/ReadOnlyRole", account))
+		role := selectRole("list_iam_users", grantedScopes)
+		roleARN := fmt.Sprintf("arn:aws:iam::%s:role/%s", account, role)
+
+		logger.Debug("aws api call", "account_id", account, "role_arn", roleARN, "command", "list_iam_users", "request_id", requestID)
+		cfg, err := authModule.GetAWSConfig(ctx, sessionID, roleARN)
 		if err != nil {
 			return "", fmt.Errorf("error getting AWS config for account %s: %v", account, err)
 		}
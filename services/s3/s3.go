@@ -3,21 +3,68 @@ package s3
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
 	"github.com/mrdankuta/aws-devops-api/auth"
 )
 
-func NewCommand(command string, accounts []string, authModule *auth.AuthModule) func() (string, error) {
-	return func() (string, error) {
+// commandRoles maps each command to the default IAM role assumed while
+// running it, so a command is bound to no more access than it needs rather
+// than every command sharing one hard-coded role.
+var commandRoles = map[string]string{
+	"check_unused_buckets": "AuditRole",
+}
+
+// adminScope is the granted scope that elevates a command to AdminRole
+// instead of its default role in commandRoles, e.g. for an operator who
+// needs to act on the buckets an audit only reports on.
+const adminScope = "s3:admin"
+
+// selectRole picks the role ARN-fragment to assume for command, given the
+// scopes the caller's token was granted. adminScope takes precedence over
+// command's default role, so GetAWSConfig impersonates no more access than
+// the caller actually has.
+func selectRole(command string, grantedScopes []string) string {
+	for _, scope := range grantedScopes {
+		if scope == adminScope {
+			return "AdminRole"
+		}
+	}
+	if role := commandRoles[command]; role != "" {
+		return role
+	}
+	return "ReadOnlyRole"
+}
+
+// RequiredScopes returns the OIDC scope AuthModule.Authorize must see
+// granted before command may run.
+func RequiredScopes(command string) ([]string, error) {
+	switch command {
+	case "check_unused_buckets":
+		return []string{"s3:check_unused_buckets"}, nil
+	default:
+		return nil, fmt.Errorf("unknown S3 command: %s", command)
+	}
+}
+
+// NewCommand returns command's executor. sessionID identifies the caller
+// whose AWS credentials GetAWSConfig should mint, and grantedScopes carries
+// the scopes that caller's token was granted (see auth.AuthModule.Authorize),
+// used by selectRole to pick the role assumed in each account.
+func NewCommand(command string, sessionID string, accounts []string, grantedScopes []string, authModule *auth.AuthModule, logger *slog.Logger) func(ctx context.Context) (string, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return func(ctx context.Context) (string, error) {
 		switch command {
 		case "check_unused_buckets":
-			ctx := context.Background()
-			unusedBuckets, err := checkUnusedBuckets(ctx, authModule, accounts)
+			unusedBuckets, err := checkUnusedBuckets(ctx, authModule, sessionID, accounts, grantedScopes, logger)
 			if err != nil {
 				return "", err
 			}
@@ -29,11 +76,12 @@ func NewCommand(command string, accounts []string, authModule *auth.AuthModule)
 	}
 }
 
-func checkUnusedBuckets(ctx context.Context, authModule *auth.AuthModule, accounts []string) (map[string][]string, error) {
+func checkUnusedBuckets(ctx context.Context, authModule *auth.AuthModule, sessionID string, accounts []string, grantedScopes []string, logger *slog.Logger) (map[string][]string, error) {
 	unusedBuckets := make(map[string][]string)
+	requestID := uuid.New().String()
 
 	for _, account := range accounts {
-		lastAccessTimes, err := getLastAccessTimes(ctx, authModule, account)
+		lastAccessTimes, err := getLastAccessTimes(ctx, authModule, sessionID, account, "check_unused_buckets", grantedScopes, requestID, logger)
 		if err != nil {
 			return nil, fmt.Errorf("error getting last access times for account %s: %w", account, err)
 		}
@@ -48,8 +96,12 @@ func checkUnusedBuckets(ctx context.Context, authModule *auth.AuthModule, accoun
 	return unusedBuckets, nil
 }
 
-func getLastAccessTimes(ctx context.Context, authModule *auth.AuthModule, accountID string) (map[string]time.Time, error) {
-	cfg, err := authModule.GetAWSConfig(ctx, accountID, fmt.Sprintf("arn:aws:iam::%s:role/ReadOnlyRole", accountID))
+func getLastAccessTimes(ctx context.Context, authModule *auth.AuthModule, sessionID, accountID, command string, grantedScopes []string, requestID string, logger *slog.Logger) (map[string]time.Time, error) {
+	role := selectRole(command, grantedScopes)
+	roleARN := fmt.Sprintf("arn:aws:iam::%s:role/%s", accountID, role)
+
+	logger.Debug("aws api call", "account_id", accountID, "role_arn", roleARN, "command", command, "request_id", requestID)
+	cfg, err := authModule.GetAWSConfig(ctx, sessionID, roleARN)
 	if err != nil {
 		return nil, fmt.Errorf("error getting AWS config: %w", err)
 	}
@@ -67,7 +119,7 @@ func getLastAccessTimes(ctx context.Context, authModule *auth.AuthModule, accoun
 	for _, bucket := range listBucketsOutput.Buckets {
 		lastAccessTime, err := getLastAccessTime(ctx, cwClient, *bucket.Name)
 		if err != nil {
-			fmt.Printf("Error getting last access time for bucket %s: %v\n", *bucket.Name, err)
+			logger.Error("error getting last access time for bucket", "account_id", accountID, "request_id", requestID, "bucket", *bucket.Name, "error", err)
 			continue
 		}
 		lastAccessTimes[*bucket.Name] = lastAccessTime
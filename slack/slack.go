@@ -2,45 +2,69 @@ package slack
 
 import (
 	"fmt"
+	"log/slog"
 
-	"github.com/sirupsen/logrus"
 	"github.com/slack-go/slack"
 )
 
-var log = logrus.New()
-
-func init() {
-	log.SetFormatter(&logrus.TextFormatter{
-		FullTimestamp: true,
-	})
-	log.SetLevel(logrus.DebugLevel)
-}
-
 type Client struct {
-	api *slack.Client
+	api    *slack.Client
+	logger *slog.Logger
 }
 
-func NewClient(token string) *Client {
+func NewClient(token string, logger *slog.Logger) *Client {
+	if logger == nil {
+		logger = slog.Default()
+	}
 	return &Client{
-		api: slack.New(token),
+		api:    slack.New(token),
+		logger: logger,
 	}
 }
 
 func (c *Client) PostMessage(channel, message string) error {
-	log.WithFields(logrus.Fields{
-		"channel": channel,
-		"message": message,
-	}).Debug("Posting message to Slack")
+	c.logger.Debug("posting message to slack", "channel", channel, "message", message)
 
 	_, _, err := c.api.PostMessage(channel, slack.MsgOptionText(message, false))
 	if err != nil {
-		log.WithFields(logrus.Fields{
-			"channel": channel,
-			"error":   err,
-		}).Error("Error posting message to Slack")
-		return fmt.Errorf("error posting message to Slack: %w", err)
+		c.logger.Error("error posting message to slack", "channel", channel, "error", err)
+		return fmt.Errorf("error posting message to slack: %w", err)
+	}
+
+	c.logger.Info("successfully posted message to slack", "channel", channel)
+	return nil
+}
+
+// StepSummary is one line item in a pipeline result notification.
+type StepSummary struct {
+	Name   string
+	Status string
+	Detail string
+}
+
+// PostStepResults posts the aggregated outcome of a multi-step task pipeline
+// as a Slack Block Kit message, one section per step, instead of a single
+// text blob.
+func (c *Client) PostStepResults(channel, taskName string, steps []StepSummary) error {
+	blocks := []slack.Block{
+		slack.NewHeaderBlock(slack.NewTextBlockObject(slack.PlainTextType, fmt.Sprintf("Pipeline: %s", taskName), false, false)),
+	}
+	for _, s := range steps {
+		text := fmt.Sprintf("*%s* — %s", s.Name, s.Status)
+		if s.Detail != "" {
+			text += "\n" + s.Detail
+		}
+		blocks = append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil))
+	}
+
+	c.logger.Debug("posting pipeline results to slack", "channel", channel, "task", taskName, "steps", len(steps))
+
+	_, _, err := c.api.PostMessage(channel, slack.MsgOptionBlocks(blocks...))
+	if err != nil {
+		c.logger.Error("error posting pipeline results to slack", "channel", channel, "error", err)
+		return fmt.Errorf("error posting pipeline results to slack: %w", err)
 	}
 
-	log.WithField("channel", channel).Info("Successfully posted message to Slack")
+	c.logger.Info("successfully posted pipeline results to slack", "channel", channel)
 	return nil
 }
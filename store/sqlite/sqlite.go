@@ -0,0 +1,213 @@
+// Package sqlite implements tasks.Store on top of a local SQLite database,
+// using the pure-Go modernc.org/sqlite driver so the binary stays cgo-free.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mrdankuta/aws-devops-api/tasks"
+	_ "modernc.org/sqlite"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS tasks (
+	id            TEXT PRIMARY KEY,
+	name          TEXT NOT NULL,
+	aws_accounts  TEXT NOT NULL,
+	service       TEXT NOT NULL,
+	command       TEXT NOT NULL,
+	steps         TEXT NOT NULL DEFAULT '[]',
+	schedule      TEXT NOT NULL,
+	slack_channel TEXT NOT NULL,
+	timeout       TEXT NOT NULL,
+	max_retries   INTEGER NOT NULL,
+	retry_delay   TEXT NOT NULL,
+	retry_backoff TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS task_executions (
+	task_id    TEXT NOT NULL,
+	attempt    INTEGER NOT NULL,
+	started_at TIMESTAMP NOT NULL,
+	ended_at   TIMESTAMP NOT NULL,
+	result     TEXT NOT NULL DEFAULT '',
+	error      TEXT NOT NULL DEFAULT '',
+	steps      TEXT NOT NULL DEFAULT '[]'
+);
+
+CREATE TABLE IF NOT EXISTS task_runs (
+	task_id      TEXT NOT NULL,
+	scheduled_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (task_id, scheduled_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_runs_scheduled_at ON task_runs(scheduled_at);
+`
+
+// runRetention bounds how long a run lease is kept in task_runs before
+// AcquireRun prunes it, so the table doesn't grow without bound over a
+// long-running scheduler's lifetime — it only needs to hold leases long
+// enough to dedupe firings across replicas, not forever.
+const runRetention = 7 * 24 * time.Hour
+
+// Store is a tasks.Store backed by SQLite.
+type Store struct {
+	db *sql.DB
+}
+
+// Open creates/migrates the SQLite database at dsn (a file path, or ":memory:").
+func Open(dsn string) (*Store, error) {
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate sqlite schema: %w", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func (s *Store) SaveTask(ctx context.Context, task tasks.StoredTask) error {
+	accounts, err := json.Marshal(task.Config.AWSAccounts)
+	if err != nil {
+		return fmt.Errorf("failed to marshal aws_accounts: %w", err)
+	}
+	steps, err := json.Marshal(task.Config.Steps)
+	if err != nil {
+		return fmt.Errorf("failed to marshal steps: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO tasks (id, name, aws_accounts, service, command, steps, schedule, slack_channel, timeout, max_retries, retry_delay, retry_backoff)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			name=excluded.name, aws_accounts=excluded.aws_accounts, service=excluded.service,
+			command=excluded.command, steps=excluded.steps, schedule=excluded.schedule, slack_channel=excluded.slack_channel,
+			timeout=excluded.timeout, max_retries=excluded.max_retries, retry_delay=excluded.retry_delay,
+			retry_backoff=excluded.retry_backoff
+	`, task.ID, task.Config.Name, string(accounts), task.Config.Service, task.Config.Command, string(steps),
+		task.Config.Schedule, task.Config.SlackChannel, task.Config.Timeout, task.Config.MaxRetries,
+		task.Config.RetryDelay, task.Config.RetryBackoff)
+	if err != nil {
+		return fmt.Errorf("failed to save task %s: %w", task.ID, err)
+	}
+	return nil
+}
+
+func (s *Store) LoadTasks(ctx context.Context) ([]tasks.StoredTask, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, name, aws_accounts, service, command, steps, schedule, slack_channel, timeout, max_retries, retry_delay, retry_backoff
+		FROM tasks
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var out []tasks.StoredTask
+	for rows.Next() {
+		var t tasks.StoredTask
+		var accounts, steps string
+		if err := rows.Scan(&t.ID, &t.Config.Name, &accounts, &t.Config.Service, &t.Config.Command, &steps,
+			&t.Config.Schedule, &t.Config.SlackChannel, &t.Config.Timeout, &t.Config.MaxRetries,
+			&t.Config.RetryDelay, &t.Config.RetryBackoff); err != nil {
+			return nil, fmt.Errorf("failed to scan task row: %w", err)
+		}
+		if err := json.Unmarshal([]byte(accounts), &t.Config.AWSAccounts); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal aws_accounts for task %s: %w", t.ID, err)
+		}
+		if err := json.Unmarshal([]byte(steps), &t.Config.Steps); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal steps for task %s: %w", t.ID, err)
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) DeleteTask(ctx context.Context, id string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM tasks WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete task %s: %w", id, err)
+	}
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM task_executions WHERE task_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete execution history for task %s: %w", id, err)
+	}
+	return nil
+}
+
+func (s *Store) AppendExecution(ctx context.Context, taskID string, attempt tasks.Attempt) error {
+	steps, err := json.Marshal(attempt.Steps)
+	if err != nil {
+		return fmt.Errorf("failed to marshal step results for task %s: %w", taskID, err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO task_executions (task_id, attempt, started_at, ended_at, result, error, steps)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, taskID, attempt.Attempt, attempt.StartedAt, attempt.EndedAt, attempt.Result, attempt.Error, string(steps))
+	if err != nil {
+		return fmt.Errorf("failed to append execution for task %s: %w", taskID, err)
+	}
+	return nil
+}
+
+func (s *Store) ListExecutions(ctx context.Context, taskID string, limit int) ([]tasks.Attempt, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT attempt, started_at, ended_at, result, error, steps FROM task_executions
+		WHERE task_id = ? ORDER BY started_at DESC LIMIT ?
+	`, taskID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list executions for task %s: %w", taskID, err)
+	}
+	defer rows.Close()
+
+	var out []tasks.Attempt
+	for rows.Next() {
+		var a tasks.Attempt
+		var steps string
+		if err := rows.Scan(&a.Attempt, &a.StartedAt, &a.EndedAt, &a.Result, &a.Error, &steps); err != nil {
+			return nil, fmt.Errorf("failed to scan execution row: %w", err)
+		}
+		if err := json.Unmarshal([]byte(steps), &a.Steps); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal step results for task %s: %w", taskID, err)
+		}
+		out = append(out, a)
+	}
+
+	// Reverse to oldest-first, matching the in-memory ring buffer ordering.
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) AcquireRun(ctx context.Context, taskID string, scheduledAt time.Time) (bool, error) {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM task_runs WHERE scheduled_at < ?`, scheduledAt.UTC().Add(-runRetention)); err != nil {
+		return false, fmt.Errorf("failed to prune expired run leases: %w", err)
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+		INSERT OR IGNORE INTO task_runs (task_id, scheduled_at) VALUES (?, ?)
+	`, taskID, scheduledAt.UTC())
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire run lease for task %s: %w", taskID, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to check run lease result for task %s: %w", taskID, err)
+	}
+	return affected > 0, nil
+}
+
+var _ tasks.Store = (*Store)(nil)
@@ -0,0 +1,48 @@
+package tasks
+
+import (
+	"sync"
+	"time"
+)
+
+// maxHistorySize bounds how many attempts are retained per task in memory.
+const maxHistorySize = 50
+
+// Attempt records the outcome of a single execution attempt of a task.
+type Attempt struct {
+	Attempt   int          `json:"attempt"`
+	StartedAt time.Time    `json:"started_at"`
+	EndedAt   time.Time    `json:"ended_at"`
+	Result    string       `json:"result,omitempty"`
+	Error     string       `json:"error,omitempty"`
+	Steps     []StepResult `json:"steps,omitempty"`
+}
+
+// history is a fixed-size ring buffer of attempts for a single task.
+type history struct {
+	mu      sync.Mutex
+	entries []Attempt
+}
+
+func newHistory() *history {
+	return &history{entries: make([]Attempt, 0, maxHistorySize)}
+}
+
+func (h *history) add(a Attempt) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.entries = append(h.entries, a)
+	if len(h.entries) > maxHistorySize {
+		h.entries = h.entries[len(h.entries)-maxHistorySize:]
+	}
+}
+
+func (h *history) snapshot() []Attempt {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	out := make([]Attempt, len(h.entries))
+	copy(out, h.entries)
+	return out
+}
@@ -0,0 +1,376 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sync"
+
+	"github.com/mrdankuta/aws-devops-api/auth"
+	"github.com/mrdankuta/aws-devops-api/config"
+	"github.com/mrdankuta/aws-devops-api/services/iam"
+	"github.com/mrdankuta/aws-devops-api/services/s3"
+)
+
+// Step when-conditions, controlling whether a step runs based on the
+// outcome of the steps it depends on.
+const (
+	WhenOnSuccess = "on_success"
+	WhenOnFailure = "on_failure"
+	WhenAlways    = "always"
+)
+
+// Step is one node in a task's execution DAG. A task with no configured
+// steps is represented internally as a single implicit step, so ExecuteTask
+// always runs a (possibly trivial) pipeline.
+type Step struct {
+	Name        string
+	Service     string
+	Command     string
+	AWSAccounts []string
+	DependsOn   []string
+	When        string
+}
+
+// StepResult records the outcome of running one step of a pipeline.
+type StepResult struct {
+	Name   string `json:"name"`
+	Status string `json:"status"` // succeeded, failed, skipped
+	Result string `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// stepResultsKey is the context key ExecuteTask uses to collect a pipeline
+// run's per-step results without changing the Task.Execute signature.
+type stepResultsKey struct{}
+
+func withStepResults(ctx context.Context, sink *[]StepResult) context.Context {
+	return context.WithValue(ctx, stepResultsKey{}, sink)
+}
+
+func stepResultsFromContext(ctx context.Context) *[]StepResult {
+	sink, _ := ctx.Value(stepResultsKey{}).(*[]StepResult)
+	return sink
+}
+
+// stepsFromConfig builds a task's step DAG from its config. A task with no
+// steps declared gets a single implicit step running its top-level
+// service/command, so the pipeline executor is the only code path for both
+// plain tasks and pipelines.
+func stepsFromConfig(cfg config.TaskConfig) []Step {
+	if len(cfg.Steps) == 0 {
+		return []Step{{
+			Name:        "default",
+			Service:     cfg.Service,
+			Command:     cfg.Command,
+			AWSAccounts: cfg.AWSAccounts,
+			When:        WhenAlways,
+		}}
+	}
+
+	steps := make([]Step, len(cfg.Steps))
+	for i, s := range cfg.Steps {
+		when := s.When
+		if when == "" {
+			when = WhenOnSuccess
+		}
+		steps[i] = Step{
+			Name:        s.Name,
+			Service:     s.Service,
+			Command:     s.Command,
+			AWSAccounts: s.AWSAccounts,
+			DependsOn:   s.DependsOn,
+			When:        when,
+		}
+	}
+	return steps
+}
+
+// validateStepDAG checks that every DependsOn name refers to a step that
+// actually exists in steps and that the dependency graph has no cycles, so
+// a config error (typo'd step name, or two steps depending on each other)
+// is rejected at load time instead of deadlocking runPipeline's goroutines
+// on a cycle that can never close its done channels.
+func validateStepDAG(steps []Step) error {
+	byName := make(map[string]Step, len(steps))
+	for _, s := range steps {
+		if _, dup := byName[s.Name]; dup {
+			return fmt.Errorf("duplicate step name: %s", s.Name)
+		}
+		byName[s.Name] = s
+	}
+	for _, s := range steps {
+		for _, dep := range s.DependsOn {
+			if _, ok := byName[dep]; !ok {
+				return fmt.Errorf("step %s depends on unknown step %s", s.Name, dep)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(steps))
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("cycle in step dependencies: %s -> %s", joinPath(path), name)
+		}
+		state[name] = visiting
+		for _, dep := range byName[name].DependsOn {
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		return nil
+	}
+	for _, s := range steps {
+		if err := visit(s.Name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func joinPath(path []string) string {
+	out := ""
+	for i, name := range path {
+		if i > 0 {
+			out += " -> "
+		}
+		out += name
+	}
+	return out
+}
+
+// stepsToConfig converts a task's runtime steps back to config form for
+// persistence, omitting the single implicit step synthesized for tasks with
+// no declared pipeline so they round-trip as plain service/command tasks.
+func stepsToConfig(steps []Step) []config.StepConfig {
+	if len(steps) == 1 && steps[0].Name == "default" {
+		return nil
+	}
+
+	cfgs := make([]config.StepConfig, len(steps))
+	for i, s := range steps {
+		cfgs[i] = config.StepConfig{
+			Name:        s.Name,
+			Service:     s.Service,
+			Command:     s.Command,
+			AWSAccounts: s.AWSAccounts,
+			DependsOn:   s.DependsOn,
+			When:        s.When,
+		}
+	}
+	return cfgs
+}
+
+// newPipelineExecutor returns the Execute closure for a task's step DAG. It
+// runs steps concurrently as soon as their dependencies finish, and resolves
+// ${steps.name.result} references in later commands from earlier steps'
+// captured results.
+func newPipelineExecutor(steps []Step, authModule *auth.AuthModule, logger *slog.Logger) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		results, err := runPipeline(ctx, steps, authModule, logger)
+		if sink := stepResultsFromContext(ctx); sink != nil {
+			*sink = results
+		}
+
+		if len(results) == 1 {
+			return results[0].Result, err
+		}
+		return formatPipelineMessage(results), err
+	}
+}
+
+// runPipeline executes steps as a DAG: every step waits on its DependsOn
+// channels, decides whether it still runs via its When condition, resolves
+// step references in its command, then runs. Independent steps run
+// concurrently.
+func runPipeline(ctx context.Context, steps []Step, authModule *auth.AuthModule, logger *slog.Logger) ([]StepResult, error) {
+	if len(steps) == 1 {
+		step := steps[0]
+		result, err := runStep(ctx, step, nil, authModule, logger)
+		if err != nil {
+			return []StepResult{{Name: step.Name, Status: "failed", Error: err.Error()}}, err
+		}
+		return []StepResult{{Name: step.Name, Status: "succeeded", Result: result}}, nil
+	}
+
+	done := make(map[string]chan struct{}, len(steps))
+	for _, s := range steps {
+		done[s.Name] = make(chan struct{})
+	}
+
+	var (
+		mu      sync.Mutex
+		results = make(map[string]StepResult, len(steps))
+		wg      sync.WaitGroup
+	)
+
+	for _, s := range steps {
+		step := s
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(done[step.Name])
+
+			depResults := make(map[string]StepResult, len(step.DependsOn))
+			for _, dep := range step.DependsOn {
+				if ch, ok := done[dep]; ok {
+					select {
+					case <-ch:
+					case <-ctx.Done():
+						mu.Lock()
+						results[step.Name] = StepResult{Name: step.Name, Status: "failed", Error: ctx.Err().Error()}
+						mu.Unlock()
+						return
+					}
+				}
+				mu.Lock()
+				depResults[dep] = results[dep]
+				mu.Unlock()
+			}
+
+			if !shouldRunStep(step.When, depResults) {
+				mu.Lock()
+				results[step.Name] = StepResult{Name: step.Name, Status: "skipped"}
+				mu.Unlock()
+				return
+			}
+
+			mu.Lock()
+			values := make(map[string]string, len(results))
+			for name, r := range results {
+				values[name] = r.Result
+			}
+			mu.Unlock()
+
+			result, err := runStep(ctx, step, values, authModule, logger)
+
+			mu.Lock()
+			if err != nil {
+				results[step.Name] = StepResult{Name: step.Name, Status: "failed", Error: err.Error()}
+			} else {
+				results[step.Name] = StepResult{Name: step.Name, Status: "succeeded", Result: result}
+			}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	ordered := make([]StepResult, len(steps))
+	var firstErr error
+	for i, s := range steps {
+		r := results[s.Name]
+		ordered[i] = r
+		if r.Status == "failed" && firstErr == nil {
+			firstErr = fmt.Errorf("step %s failed: %s", s.Name, r.Error)
+		}
+	}
+	return ordered, firstErr
+}
+
+// shouldRunStep decides whether a step executes given its dependencies'
+// outcomes: on_success (default) requires every dependency to have
+// succeeded, on_failure requires at least one to have failed, and always
+// runs regardless. A step with no dependencies always runs.
+func shouldRunStep(when string, depResults map[string]StepResult) bool {
+	if len(depResults) == 0 {
+		return true
+	}
+
+	anyFailed := false
+	for _, r := range depResults {
+		if r.Status == "failed" {
+			anyFailed = true
+		}
+	}
+
+	switch when {
+	case WhenAlways:
+		return true
+	case WhenOnFailure:
+		return anyFailed
+	default:
+		return !anyFailed
+	}
+}
+
+func runStep(ctx context.Context, step Step, values map[string]string, authModule *auth.AuthModule, logger *slog.Logger) (string, error) {
+	command := substituteStepRefs(step.Command, values)
+
+	var requiredScopes []string
+	var err error
+	switch step.Service {
+	case "s3":
+		requiredScopes, err = s3.RequiredScopes(command)
+	case "iam":
+		requiredScopes, err = iam.RequiredScopes(command)
+	default:
+		return "", fmt.Errorf("unknown service: %s", step.Service)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// step.AWSAccounts are AWS account numbers the step acts on, not the
+	// caller's identity — authorization and the token cache are keyed by the
+	// session ID of the browser session that triggered this run, carried on
+	// ctx since it was read from the HTTP layer well before pipeline
+	// execution reached this step.
+	sessionID, ok := auth.SessionIDFromContext(ctx)
+	if !ok {
+		return "", fmt.Errorf("no session associated with this run")
+	}
+	grantedScopes, err := authModule.Authorize(ctx, sessionID, requiredScopes)
+	if err != nil {
+		return "", fmt.Errorf("not authorized to run %s.%s: %w", step.Service, command, err)
+	}
+
+	var execute func(ctx context.Context) (string, error)
+	switch step.Service {
+	case "s3":
+		execute = s3.NewCommand(command, sessionID, step.AWSAccounts, grantedScopes, authModule, logger)
+	case "iam":
+		execute = iam.NewCommand(command, sessionID, step.AWSAccounts, grantedScopes, authModule, logger)
+	}
+
+	return execute(ctx)
+}
+
+var stepRefPattern = regexp.MustCompile(`\$\{steps\.([^.}]+)\.result\}`)
+
+// substituteStepRefs replaces ${steps.name.result} references in command
+// with the named step's captured result. It is re-run per step so a step
+// can consume the output of any step it depends on.
+func substituteStepRefs(command string, values map[string]string) string {
+	return stepRefPattern.ReplaceAllStringFunc(command, func(match string) string {
+		name := stepRefPattern.FindStringSubmatch(match)[1]
+		return values[name]
+	})
+}
+
+// formatPipelineMessage aggregates a multi-step run into a single
+// human-readable summary, used for history and as a fallback when Slack
+// block notification isn't available.
+func formatPipelineMessage(results []StepResult) string {
+	message := "Pipeline results:\n"
+	for _, r := range results {
+		message += fmt.Sprintf("- %s: %s\n", r.Name, r.Status)
+		if r.Result != "" {
+			message += fmt.Sprintf("  %s\n", r.Result)
+		}
+		if r.Error != "" {
+			message += fmt.Sprintf("  error: %s\n", r.Error)
+		}
+	}
+	return message
+}
@@ -0,0 +1,37 @@
+package tasks
+
+import (
+	"context"
+	"time"
+
+	"github.com/mrdankuta/aws-devops-api/config"
+)
+
+// StoredTask is the durable representation of a task: its generated ID plus
+// the config it was created from. Task itself is not persisted directly
+// because it holds runtime-only state (a parsed cron.Schedule and the
+// Execute closure).
+type StoredTask struct {
+	ID     string
+	Config config.TaskConfig
+}
+
+// Store persists tasks and their execution history outside the process, so
+// CreateTask/UpdateTask/DeleteTask survive a restart and multiple replicas
+// can share state. Implementations must be safe for concurrent use.
+type Store interface {
+	// SaveTask upserts a task by ID.
+	SaveTask(ctx context.Context, task StoredTask) error
+	// LoadTasks returns every persisted task.
+	LoadTasks(ctx context.Context) ([]StoredTask, error)
+	// DeleteTask removes a task and its history.
+	DeleteTask(ctx context.Context, id string) error
+	// AppendExecution records one execution attempt for a task.
+	AppendExecution(ctx context.Context, taskID string, attempt Attempt) error
+	// ListExecutions returns the most recent attempts for a task, oldest first.
+	ListExecutions(ctx context.Context, taskID string, limit int) ([]Attempt, error)
+	// AcquireRun claims the right to execute taskID for the given cron firing,
+	// so that only one replica runs a given scheduled execution. It returns
+	// false (without error) when another replica already holds the lease.
+	AcquireRun(ctx context.Context, taskID string, scheduledAt time.Time) (bool, error)
+}
@@ -1,21 +1,42 @@
 package tasks
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
+	"math"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/mrdankuta/aws-devops-api/auth"
 	"github.com/mrdankuta/aws-devops-api/config"
-	"github.com/mrdankuta/aws-devops-api/services/iam"
-	"github.com/mrdankuta/aws-devops-api/services/s3"
+	"github.com/mrdankuta/aws-devops-api/events"
+	"github.com/mrdankuta/aws-devops-api/notify"
+	"github.com/mrdankuta/aws-devops-api/slack"
 	"github.com/robfig/cron/v3"
 )
 
+const (
+	defaultTimeout      = 30 * time.Second
+	defaultRetryDelay   = 5 * time.Second
+	defaultRetryBackoff = "linear"
+	backoffExponential  = "exponential"
+)
+
 type TaskManager struct {
-	tasks      map[string]Task
-	authModule *auth.AuthModule
-	cron       *cron.Cron
+	tasks             map[string]Task
+	authModule        *auth.AuthModule
+	cron              *cron.Cron
+	slackClient       *slack.Client
+	deadLetterChannel string
+	store             Store
+	publisher         *events.Publisher
+	notifier          *notify.Registry
+	logger            *slog.Logger
+
+	historyMu sync.Mutex
+	history   map[string]*history
 }
 
 type Task struct {
@@ -24,56 +45,85 @@ type Task struct {
 	AWSAccounts    []string
 	Service        string
 	Command        string
+	Steps          []Step
 	Schedule       cron.Schedule
 	ScheduleString string
 	SlackChannel   string
-	Execute        func() (string, error)
+	Notifications  []config.NotificationConfig
+	Timeout        time.Duration
+	MaxRetries     int
+	RetryDelay     time.Duration
+	RetryBackoff   string
+	Execute        func(ctx context.Context) (string, error)
 	cronEntryID    int
 }
 
-func NewTaskManager(taskConfigs *[]config.TaskConfig, authModule *auth.AuthModule) *TaskManager {
+// NewTaskManager loads tasks from store (if configured) and merges in any
+// config.yaml seed tasks not already present there, so the store becomes the
+// durable source of truth across restarts and replicas. logger is passed
+// through to every s3/iam command a task's steps run, for per-call debug
+// logging; a nil logger falls back to slog.Default().
+func NewTaskManager(taskConfigs *[]config.TaskConfig, authModule *auth.AuthModule, slackClient *slack.Client, slackCfg *config.SlackConfig, store Store, publisher *events.Publisher, notifier *notify.Registry, logger *slog.Logger) *TaskManager {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
 	tm := &TaskManager{
-		tasks:      make(map[string]Task),
-		authModule: authModule,
-		cron:       cron.New(),
+		tasks:             make(map[string]Task),
+		authModule:        authModule,
+		cron:              cron.New(),
+		slackClient:       slackClient,
+		deadLetterChannel: slackCfg.DeadLetterChannel,
+		store:             store,
+		publisher:         publisher,
+		notifier:          notifier,
+		logger:            logger,
+		history:           make(map[string]*history),
+	}
+
+	ctx := context.Background()
+	seenNames := make(map[string]bool)
+
+	if store != nil {
+		storedTasks, err := store.LoadTasks(ctx)
+		if err != nil {
+			fmt.Printf("Error loading tasks from store: %v\n", err)
+		}
+		for _, st := range storedTasks {
+			task, err := buildTask(st.ID, st.Config, authModule, logger)
+			if err != nil {
+				fmt.Printf("Error rebuilding stored task %s: %v\n", st.Config.Name, err)
+				continue
+			}
+			tm.addTask(task)
+			tm.hydrateHistory(ctx, task.ID)
+			seenNames[task.Name] = true
+			fmt.Printf("Loaded task from store: ID=%s, Name=%s\n", task.ID, task.Name)
+		}
 	}
 
 	fmt.Printf("Initializing TaskManager with %d task configs\n", len(*taskConfigs))
 
 	for _, cfg := range *taskConfigs {
-		schedule, err := cron.ParseStandard(cfg.Schedule)
-		if err != nil {
-			fmt.Printf("Error parsing schedule for task %s: %v\n", cfg.Name, err)
+		if seenNames[cfg.Name] {
 			continue
 		}
 
 		taskID := uuid.New().String()
-		task := Task{
-			ID:             taskID,
-			Name:           cfg.Name,
-			AWSAccounts:    cfg.AWSAccounts,
-			Service:        cfg.Service,
-			Command:        cfg.Command,
-			Schedule:       schedule,
-			ScheduleString: cfg.Schedule,
-			SlackChannel:   cfg.SlackChannel,
-		}
-
-		switch cfg.Service {
-		case "s3":
-			task.Execute = s3.NewCommand(cfg.Command, cfg.AWSAccounts, authModule)
-		case "iam":
-			task.Execute = iam.NewCommand(cfg.Command, cfg.AWSAccounts, authModule)
-		default:
-			fmt.Printf("Unknown service for task %s: %s\n", cfg.Name, cfg.Service)
+		task, err := buildTask(taskID, cfg, authModule, logger)
+		if err != nil {
+			fmt.Printf("Error creating task %s: %v\n", cfg.Name, err)
 			continue
 		}
 
-		tm.tasks[taskID] = task
+		tm.addTask(task)
 		fmt.Printf("Added task: ID=%s, Name=%s\n", taskID, task.Name)
-		tm.cron.Schedule(schedule, cron.FuncJob(func() {
-			tm.ExecuteTask(taskID)
-		}))
+
+		if tm.store != nil {
+			if err := tm.store.SaveTask(ctx, StoredTask{ID: taskID, Config: cfg}); err != nil {
+				fmt.Printf("Error persisting seed task %s: %v\n", cfg.Name, err)
+			}
+		}
 	}
 
 	fmt.Printf("TaskManager initialized with %d tasks\n", len(tm.tasks))
@@ -81,15 +131,98 @@ func NewTaskManager(taskConfigs *[]config.TaskConfig, authModule *auth.AuthModul
 	return tm
 }
 
-func (tm *TaskManager) GetDueTasks() []Task {
-	var dueTasks []Task
-	now := time.Now()
-	for _, task := range tm.tasks {
-		if task.Schedule.Next(now).Sub(now) < time.Minute {
-			dueTasks = append(dueTasks, task)
+// buildTask constructs a runnable Task (parsed schedule + Execute closure)
+// from its config. Shared by seeding from config.yaml, store hydration, and CreateTask.
+func buildTask(taskID string, cfg config.TaskConfig, authModule *auth.AuthModule, logger *slog.Logger) (Task, error) {
+	schedule, err := cron.ParseStandard(cfg.Schedule)
+	if err != nil {
+		return Task{}, fmt.Errorf("error parsing schedule: %w", err)
+	}
+
+	steps := stepsFromConfig(cfg)
+	for _, s := range steps {
+		switch s.Service {
+		case "s3", "iam":
+		default:
+			return Task{}, fmt.Errorf("unknown service: %s", s.Service)
+		}
+	}
+	if err := validateStepDAG(steps); err != nil {
+		return Task{}, fmt.Errorf("invalid step pipeline: %w", err)
+	}
+
+	task := Task{
+		ID:             taskID,
+		Name:           cfg.Name,
+		AWSAccounts:    cfg.AWSAccounts,
+		Service:        cfg.Service,
+		Command:        cfg.Command,
+		Steps:          steps,
+		Schedule:       schedule,
+		ScheduleString: cfg.Schedule,
+		SlackChannel:   cfg.SlackChannel,
+		Notifications:  cfg.Notifications,
+		Timeout:        resolveTimeout(cfg.Timeout),
+		MaxRetries:     cfg.MaxRetries,
+		RetryDelay:     resolveRetryDelay(cfg.RetryDelay),
+		RetryBackoff:   resolveRetryBackoff(cfg.RetryBackoff),
+	}
+	task.Execute = newPipelineExecutor(steps, authModule, logger)
+
+	return task, nil
+}
+
+// addTask registers task in the map and schedules its cron job, gated by a
+// store-backed run lease so only one replica executes a given firing.
+func (tm *TaskManager) addTask(task Task) {
+	tm.tasks[task.ID] = task
+	tm.cron.Schedule(task.Schedule, cron.FuncJob(func() {
+		tm.runScheduled(task.ID)
+	}))
+}
+
+// runScheduled is invoked by cron. When a store is configured it first
+// acquires a run lease for this firing so that, across replicas sharing the
+// same store, only one of them actually executes the task.
+func (tm *TaskManager) runScheduled(taskID string) {
+	task, exists := tm.tasks[taskID]
+	if !exists {
+		return
+	}
+
+	if tm.store != nil {
+		acquired, err := tm.store.AcquireRun(context.Background(), taskID, time.Now().Truncate(time.Minute))
+		if err != nil {
+			fmt.Printf("Error acquiring run lease for task %s: %v\n", task.Name, err)
+			return
+		}
+		if !acquired {
+			fmt.Printf("Skipping task %s: run lease already claimed by another replica\n", task.Name)
+			return
 		}
 	}
-	return dueTasks
+
+	tm.publishEvent(events.TypeTaskScheduled, task, events.TaskEventData{})
+	// A cron firing has no browser session to authorize against, so it runs
+	// as the configured system account instead (see
+	// auth.AuthModule.SystemSessionID).
+	ctx := auth.ContextWithSessionID(context.Background(), tm.authModule.SystemSessionID())
+	tm.ExecuteTask(ctx, taskID)
+}
+
+func (tm *TaskManager) hydrateHistory(ctx context.Context, taskID string) {
+	if tm.store == nil {
+		return
+	}
+	attempts, err := tm.store.ListExecutions(ctx, taskID, maxHistorySize)
+	if err != nil {
+		fmt.Printf("Error loading execution history for task %s: %v\n", taskID, err)
+		return
+	}
+	h := tm.getHistory(taskID)
+	for _, a := range attempts {
+		h.add(a)
+	}
 }
 
 func (tm *TaskManager) GetAllTasks() []Task {
@@ -107,53 +240,226 @@ func (tm *TaskManager) GetTask(id string) (Task, bool) {
 	return task, exists
 }
 
-func (tm *TaskManager) ExecuteTask(id string) (string, error) {
+// ExecuteTask runs task id to completion (with retries), under a deadline
+// derived from ctx and task.Timeout. ctx also carries the session ID of the
+// caller that triggered this run (see auth.ContextWithSessionID), so steps
+// that call AWS APIs authorize and cache tokens under that session rather
+// than a scheduled run's background context.
+func (tm *TaskManager) ExecuteTask(ctx context.Context, id string) (string, error) {
 	task, exists := tm.tasks[id]
 	if !exists {
 		return "", fmt.Errorf("task with ID %s not found", id)
 	}
 
-	result, err := task.Execute()
-	if err != nil {
-		fmt.Printf("Error executing task %s: %v\n", task.Name, err)
-		return "", err
+	tm.publishEvent(events.TypeTaskStarted, task, events.TaskEventData{})
+
+	var lastErr error
+	for attempt := 0; attempt <= task.MaxRetries; attempt++ {
+		ctx, cancel := context.WithTimeout(ctx, task.Timeout)
+		var stepResults []StepResult
+		ctx = withStepResults(ctx, &stepResults)
+		started := time.Now()
+		result, err := task.Execute(ctx)
+		ended := time.Now()
+		cancel()
+		duration := ended.Sub(started).Milliseconds()
+
+		if err == nil {
+			tm.recordAttempt(id, Attempt{Attempt: attempt, StartedAt: started, EndedAt: ended, Result: result, Steps: stepResults})
+			fmt.Printf("Task %s executed successfully: %s\n", task.Name, result)
+			tm.notifySuccess(task, result, stepResults)
+			tm.dispatchNotifications(task, true, result, "")
+			tm.publishEvent(events.TypeTaskSucceeded, task, events.TaskEventData{Attempt: attempt, DurationMS: duration, Result: result})
+			return result, nil
+		}
+
+		tm.recordAttempt(id, Attempt{Attempt: attempt, StartedAt: started, EndedAt: ended, Error: err.Error(), Steps: stepResults})
+		lastErr = err
+		fmt.Printf("Error executing task %s (attempt %d/%d): %v\n", task.Name, attempt+1, task.MaxRetries+1, err)
+
+		if attempt == task.MaxRetries {
+			break
+		}
+		tm.publishEvent(events.TypeTaskRetried, task, events.TaskEventData{Attempt: attempt, DurationMS: duration, Error: err.Error()})
+		time.Sleep(retryDelay(task.RetryDelay, task.RetryBackoff, attempt))
 	}
 
-	fmt.Printf("Task %s executed successfully: %s\n", task.Name, result)
-	return result, nil
+	tm.publishEvent(events.TypeTaskFailed, task, events.TaskEventData{Attempt: task.MaxRetries, Error: lastErr.Error()})
+	tm.reportDeadLetter(task, lastErr)
+	return "", lastErr
 }
 
-func (tm *TaskManager) CreateTask(cfg config.TaskConfig) (Task, error) {
-	schedule, err := cron.ParseStandard(cfg.Schedule)
+// publishEvent fills in the task identity fields and fans data out to every
+// configured CloudEvents sink. A nil publisher (no sinks configured) is a no-op.
+func (tm *TaskManager) publishEvent(eventType string, task Task, data events.TaskEventData) {
+	if tm.publisher == nil {
+		return
+	}
+	data.TaskID = task.ID
+	data.TaskName = task.Name
+	data.AWSAccounts = task.AWSAccounts
+	data.Service = task.Service
+	data.Command = task.Command
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := tm.publisher.Publish(ctx, eventType, data); err != nil {
+		fmt.Printf("Error publishing %s event for task %s: %v\n", eventType, task.Name, err)
+	}
+}
+
+// recordAttempt appends to the in-memory ring buffer and, if a store is
+// configured, persists the attempt so it survives restarts.
+func (tm *TaskManager) recordAttempt(taskID string, a Attempt) {
+	tm.getHistory(taskID).add(a)
+	if tm.store != nil {
+		if err := tm.store.AppendExecution(context.Background(), taskID, a); err != nil {
+			fmt.Printf("Error persisting execution history for task %s: %v\n", taskID, err)
+		}
+	}
+}
+
+// notifySuccess posts a task's result to Slack. A pipeline of more than one
+// step is posted as a Block Kit message with one section per step; a plain
+// task posts its result as text, as before.
+func (tm *TaskManager) notifySuccess(task Task, result string, steps []StepResult) {
+	if tm.slackClient == nil || task.SlackChannel == "" {
+		return
+	}
+
+	if len(steps) > 1 {
+		summaries := make([]slack.StepSummary, len(steps))
+		for i, s := range steps {
+			detail := s.Result
+			if s.Status == "failed" {
+				detail = s.Error
+			}
+			summaries[i] = slack.StepSummary{Name: s.Name, Status: s.Status, Detail: detail}
+		}
+		if err := tm.slackClient.PostStepResults(task.SlackChannel, task.Name, summaries); err != nil {
+			fmt.Printf("Error posting pipeline results for task %s to Slack: %v\n", task.Name, err)
+		}
+		return
+	}
+
+	if err := tm.slackClient.PostMessage(task.SlackChannel, result); err != nil {
+		fmt.Printf("Error posting result for task %s to Slack: %v\n", task.Name, err)
+	}
+}
+
+// GetTaskHistory returns the recorded execution attempts for a task, most recent last.
+func (tm *TaskManager) GetTaskHistory(id string) ([]Attempt, error) {
+	if _, exists := tm.tasks[id]; !exists {
+		return nil, fmt.Errorf("task with ID %s not found", id)
+	}
+	return tm.getHistory(id).snapshot(), nil
+}
+
+func (tm *TaskManager) getHistory(id string) *history {
+	tm.historyMu.Lock()
+	defer tm.historyMu.Unlock()
+
+	h, ok := tm.history[id]
+	if !ok {
+		h = newHistory()
+		tm.history[id] = h
+	}
+	return h
+}
+
+func (tm *TaskManager) reportDeadLetter(task Task, err error) {
+	tm.publishEvent(events.TypeTaskDeadlettered, task, events.TaskEventData{Attempt: task.MaxRetries, Error: err.Error()})
+	tm.dispatchNotifications(task, false, "", err.Error())
+
+	if tm.slackClient == nil || tm.deadLetterChannel == "" {
+		return
+	}
+
+	message := fmt.Sprintf("Task %s (%s) failed after %d attempt(s): %v", task.Name, task.ID, task.MaxRetries+1, err)
+	if postErr := tm.slackClient.PostMessage(tm.deadLetterChannel, message); postErr != nil {
+		fmt.Printf("Error posting dead-letter message for task %s: %v\n", task.Name, postErr)
+	}
+}
+
+// dispatchNotifications fans a task's final result out to its configured
+// notify.Registry sinks, beyond the legacy SlackChannel posted by
+// notifySuccess/reportDeadLetter above. A nil notifier or an empty
+// Notifications list is a no-op.
+func (tm *TaskManager) dispatchNotifications(task Task, success bool, result, errMsg string) {
+	if tm.notifier == nil || len(task.Notifications) == 0 {
+		return
+	}
+
+	data := notify.Data{
+		TaskID:   task.ID,
+		TaskName: task.Name,
+		Service:  task.Service,
+		Command:  task.Command,
+		Success:  success,
+		Result:   result,
+		Error:    errMsg,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := tm.notifier.Dispatch(ctx, task.Notifications, data); err != nil {
+		fmt.Printf("Error dispatching notifications for task %s: %v\n", task.Name, err)
+	}
+}
+
+func resolveTimeout(s string) time.Duration {
+	if s == "" {
+		return defaultTimeout
+	}
+	d, err := time.ParseDuration(s)
 	if err != nil {
-		return Task{}, fmt.Errorf("error parsing schedule: %w", err)
+		fmt.Printf("Invalid timeout %q, using default %s: %v\n", s, defaultTimeout, err)
+		return defaultTimeout
+	}
+	return d
+}
+
+func resolveRetryDelay(s string) time.Duration {
+	if s == "" {
+		return defaultRetryDelay
 	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		fmt.Printf("Invalid retry_delay %q, using default %s: %v\n", s, defaultRetryDelay, err)
+		return defaultRetryDelay
+	}
+	return d
+}
 
-	taskID := uuid.New().String()
-	task := Task{
-		ID:             taskID,
-		Name:           cfg.Name,
-		AWSAccounts:    cfg.AWSAccounts,
-		Service:        cfg.Service,
-		Command:        cfg.Command,
-		Schedule:       schedule,
-		ScheduleString: cfg.Schedule,
-		SlackChannel:   cfg.SlackChannel,
+func resolveRetryBackoff(s string) string {
+	if s == backoffExponential {
+		return backoffExponential
 	}
+	return defaultRetryBackoff
+}
 
-	switch cfg.Service {
-	case "s3":
-		task.Execute = s3.NewCommand(cfg.Command, cfg.AWSAccounts, tm.authModule)
-	case "iam":
-		task.Execute = iam.NewCommand(cfg.Command, cfg.AWSAccounts, tm.authModule)
-	default:
-		return Task{}, fmt.Errorf("unknown service: %s", cfg.Service)
+// retryDelay computes how long to sleep before the next attempt: RetryDelay * backoff^attempt.
+func retryDelay(base time.Duration, backoff string, attempt int) time.Duration {
+	if backoff == backoffExponential {
+		return time.Duration(float64(base) * math.Pow(2, float64(attempt)))
 	}
+	return base * time.Duration(attempt+1)
+}
 
-	tm.tasks[taskID] = task
-	tm.cron.Schedule(schedule, cron.FuncJob(func() {
-		tm.ExecuteTask(taskID)
-	}))
+func (tm *TaskManager) CreateTask(cfg config.TaskConfig) (Task, error) {
+	taskID := uuid.New().String()
+	task, err := buildTask(taskID, cfg, tm.authModule, tm.logger)
+	if err != nil {
+		return Task{}, err
+	}
+
+	tm.addTask(task)
+
+	if tm.store != nil {
+		if err := tm.store.SaveTask(context.Background(), StoredTask{ID: taskID, Config: cfg}); err != nil {
+			fmt.Printf("Error persisting task %s: %v\n", taskID, err)
+		}
+	}
 
 	return task, nil
 }
@@ -170,7 +476,7 @@ func (tm *TaskManager) UpdateTask(id string, updatedTask Task) error {
 
 	// Schedule the new job
 	entryID, err := tm.cron.AddFunc(updatedTask.ScheduleString, func() {
-		tm.ExecuteTask(id)
+		tm.runScheduled(id)
 	})
 	if err != nil {
 		return fmt.Errorf("failed to schedule updated task: %w", err)
@@ -179,6 +485,27 @@ func (tm *TaskManager) UpdateTask(id string, updatedTask Task) error {
 	// Update the task in the map
 	updatedTask.cronEntryID = int(entryID)
 	tm.tasks[id] = updatedTask
+
+	if tm.store != nil {
+		cfg := config.TaskConfig{
+			Name:          updatedTask.Name,
+			AWSAccounts:   updatedTask.AWSAccounts,
+			Service:       updatedTask.Service,
+			Command:       updatedTask.Command,
+			Steps:         stepsToConfig(updatedTask.Steps),
+			Schedule:      updatedTask.ScheduleString,
+			SlackChannel:  updatedTask.SlackChannel,
+			Notifications: updatedTask.Notifications,
+			Timeout:       updatedTask.Timeout.String(),
+			MaxRetries:    updatedTask.MaxRetries,
+			RetryDelay:    updatedTask.RetryDelay.String(),
+			RetryBackoff:  updatedTask.RetryBackoff,
+		}
+		if err := tm.store.SaveTask(context.Background(), StoredTask{ID: id, Config: cfg}); err != nil {
+			fmt.Printf("Error persisting updated task %s: %v\n", id, err)
+		}
+	}
+
 	return nil
 }
 
@@ -194,5 +521,12 @@ func (tm *TaskManager) DeleteTask(id string) error {
 
 	// Remove the task from the map
 	delete(tm.tasks, id)
+
+	if tm.store != nil {
+		if err := tm.store.DeleteTask(context.Background(), id); err != nil {
+			fmt.Printf("Error deleting persisted task %s: %v\n", id, err)
+		}
+	}
+
 	return nil
 }
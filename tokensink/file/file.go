@@ -0,0 +1,82 @@
+// Package file implements auth.TokenSink on top of the local filesystem,
+// writing one encrypted blob per account so a single leaked file only
+// exposes that one account's token.
+package file
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mrdankuta/aws-devops-api/auth"
+)
+
+// Sink is an auth.TokenSink backed by one file per account under Dir.
+type Sink struct {
+	dir string
+}
+
+// Open returns a Sink rooted at dir, creating it (and any parents) if it
+// doesn't already exist.
+func Open(dir string) (*Sink, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create token sink dir %s: %w", dir, err)
+	}
+	return &Sink{dir: dir}, nil
+}
+
+// path maps accountID to a file under dir, encoding it with unpadded
+// base64url so arbitrary account identifiers (email addresses, etc.) round
+// trip through List without collisions or path traversal.
+func (s *Sink) path(accountID string) string {
+	return filepath.Join(s.dir, base64.RawURLEncoding.EncodeToString([]byte(accountID))+".token")
+}
+
+func (s *Sink) Write(ctx context.Context, accountID string, blob []byte) error {
+	if err := os.WriteFile(s.path(accountID), blob, 0600); err != nil {
+		return fmt.Errorf("failed to write token for %s: %w", accountID, err)
+	}
+	return nil
+}
+
+func (s *Sink) Read(ctx context.Context, accountID string) ([]byte, error) {
+	blob, err := os.ReadFile(s.path(accountID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, auth.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to read token for %s: %w", accountID, err)
+	}
+	return blob, nil
+}
+
+func (s *Sink) Delete(ctx context.Context, accountID string) error {
+	if err := os.Remove(s.path(accountID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete token for %s: %w", accountID, err)
+	}
+	return nil
+}
+
+func (s *Sink) List(ctx context.Context) ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list token sink dir %s: %w", s.dir, err)
+	}
+
+	var accountIDs []string
+	for _, e := range entries {
+		name := strings.TrimSuffix(e.Name(), ".token")
+		if name == e.Name() {
+			continue // not one of ours
+		}
+		decoded, err := base64.RawURLEncoding.DecodeString(name)
+		if err != nil {
+			continue
+		}
+		accountIDs = append(accountIDs, string(decoded))
+	}
+	return accountIDs, nil
+}
@@ -0,0 +1,225 @@
+// Package k8ssecret implements auth.TokenSink on top of the Kubernetes API,
+// writing one Secret per account so a compromised Secret only exposes that
+// one account's token. It talks to the API server directly over its
+// in-cluster service account rather than depending on client-go.
+package k8ssecret
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mrdankuta/aws-devops-api/auth"
+)
+
+// serviceAccountDir is where kubelet mounts the pod's service account
+// credentials, per https://kubernetes.io/docs/tasks/run-application/access-api-from-pod/.
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// tokenBlobKey is the key the encrypted blob is stored under in each
+// Secret's data map.
+const tokenBlobKey = "blob"
+
+// Sink is an auth.TokenSink backed by one Kubernetes Secret per account in
+// Namespace, named "<NamePrefix><hex(accountID)>".
+type Sink struct {
+	namespace  string
+	namePrefix string
+	apiServer  string
+	token      string
+	httpClient *http.Client
+}
+
+// Open builds a Sink authenticated as the pod's in-cluster service account.
+// namePrefix is prepended to the hex-encoded account ID to form each
+// Secret's name.
+func Open(namespace, namePrefix string) (*Sink, error) {
+	token, err := os.ReadFile(serviceAccountDir + "/token")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account token: %w", err)
+	}
+
+	caCert, err := os.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account CA cert: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("failed to parse service account CA cert")
+	}
+
+	host := os.Getenv("KUBERNETES_SERVICE_HOST")
+	port := os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("KUBERNETES_SERVICE_HOST/KUBERNETES_SERVICE_PORT not set; not running in-cluster")
+	}
+
+	return &Sink{
+		namespace:  namespace,
+		namePrefix: namePrefix,
+		apiServer:  fmt.Sprintf("https://%s:%s", host, port),
+		token:      strings.TrimSpace(string(token)),
+		httpClient: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+		},
+	}, nil
+}
+
+type secretObject struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Metadata   secretMetadata    `json:"metadata"`
+	Type       string            `json:"type"`
+	Data       map[string]string `json:"data"`
+}
+
+type secretMetadata struct {
+	Name            string `json:"name"`
+	Namespace       string `json:"namespace"`
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+type secretList struct {
+	Items []secretObject `json:"items"`
+}
+
+func (s *Sink) secretName(accountID string) string {
+	return s.namePrefix + hex.EncodeToString([]byte(accountID))
+}
+
+func (s *Sink) secretsURL() string {
+	return fmt.Sprintf("%s/api/v1/namespaces/%s/secrets", s.apiServer, s.namespace)
+}
+
+func (s *Sink) secretURL(name string) string {
+	return fmt.Sprintf("%s/%s", s.secretsURL(), name)
+}
+
+func (s *Sink) Write(ctx context.Context, accountID string, blob []byte) error {
+	secret := secretObject{
+		APIVersion: "v1",
+		Kind:       "Secret",
+		Metadata:   secretMetadata{Name: s.secretName(accountID), Namespace: s.namespace},
+		Type:       "Opaque",
+		Data:       map[string]string{tokenBlobKey: base64.StdEncoding.EncodeToString(blob)},
+	}
+
+	existing, err := s.get(ctx, secret.Metadata.Name)
+	if err == nil {
+		secret.Metadata.ResourceVersion = existing.Metadata.ResourceVersion
+		return s.do(ctx, http.MethodPut, s.secretURL(secret.Metadata.Name), secret, nil)
+	}
+	if !errors.Is(err, auth.ErrNotFound) {
+		return err
+	}
+	return s.do(ctx, http.MethodPost, s.secretsURL(), secret, nil)
+}
+
+func (s *Sink) Read(ctx context.Context, accountID string) ([]byte, error) {
+	secret, err := s.get(ctx, s.secretName(accountID))
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, ok := secret.Data[tokenBlobKey]
+	if !ok {
+		return nil, auth.ErrNotFound
+	}
+	blob, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode secret data for %s: %w", accountID, err)
+	}
+	return blob, nil
+}
+
+func (s *Sink) Delete(ctx context.Context, accountID string) error {
+	err := s.do(ctx, http.MethodDelete, s.secretURL(s.secretName(accountID)), nil, nil)
+	if err != nil && !errors.Is(err, auth.ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+func (s *Sink) List(ctx context.Context) ([]string, error) {
+	var list secretList
+	if err := s.do(ctx, http.MethodGet, s.secretsURL(), nil, &list); err != nil {
+		return nil, err
+	}
+
+	var accountIDs []string
+	for _, item := range list.Items {
+		suffix := strings.TrimPrefix(item.Metadata.Name, s.namePrefix)
+		if suffix == item.Metadata.Name {
+			continue // not one of ours
+		}
+		decoded, err := hex.DecodeString(suffix)
+		if err != nil {
+			continue
+		}
+		accountIDs = append(accountIDs, string(decoded))
+	}
+	return accountIDs, nil
+}
+
+func (s *Sink) get(ctx context.Context, name string) (*secretObject, error) {
+	var secret secretObject
+	if err := s.do(ctx, http.MethodGet, s.secretURL(name), nil, &secret); err != nil {
+		return nil, err
+	}
+	return &secret, nil
+}
+
+// do issues an authenticated request against the Kubernetes API server,
+// marshaling body (if any) as the request and unmarshaling the response
+// into out (if non-nil). A 404 response is surfaced as auth.ErrNotFound.
+func (s *Sink) do(ctx context.Context, method, url string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return fmt.Errorf("failed to build kubernetes API request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call kubernetes API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return auth.ErrNotFound
+	}
+	if resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("kubernetes API %s %s returned status %d: %s", method, url, resp.StatusCode, string(data))
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("failed to decode kubernetes API response: %w", err)
+		}
+	}
+	return nil
+}